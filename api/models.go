@@ -0,0 +1,180 @@
+package api
+
+import (
+	"errors"
+	"github.com/nikolayk812/shopware-orders-scanner/checks"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	"github.com/nikolayk812/shopware-orders-scanner/orders"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	"sync"
+	"time"
+)
+
+const (
+	statusRunning   = "running"
+	statusSucceeded = "succeeded"
+	statusFailed    = "failed"
+)
+
+// scanRequest selects orders like sources.FilterRequest: by numbers or ids, otherwise by states,
+// otherwise by the from-to window. A window without include flags includes all kinds of changes.
+type scanRequest struct {
+	From                      *time.Time          `json:"from,omitempty"`
+	To                        *time.Time          `json:"to,omitempty"`
+	IncludeCreated            bool                `json:"includeCreated,omitempty"`
+	IncludeUpdated            bool                `json:"includeUpdated,omitempty"`
+	IncludeDeliveryUpdated    bool                `json:"includeDeliveryUpdated,omitempty"`
+	IncludeTransactionUpdated bool                `json:"includeTransactionUpdated,omitempty"`
+	OrderIDs                  []string            `json:"orderIds,omitempty"`
+	OrderNumbers              []string            `json:"orderNumbers,omitempty"`
+	States                    []domain.OrderState `json:"states,omitempty"`
+}
+
+func (r scanRequest) filter() (sources.FilterRequest, error) {
+	f := sources.FilterRequest{
+		IncludeCreated:            r.IncludeCreated,
+		IncludeUpdated:            r.IncludeUpdated,
+		IncludeDeliveryUpdated:    r.IncludeDeliveryUpdated,
+		IncludeTransactionUpdated: r.IncludeTransactionUpdated,
+		OrderIDs:                  r.OrderIDs,
+		OrderNumbers:              r.OrderNumbers,
+		States:                    r.States,
+	}
+	if f.IsLookup() || f.IsSweep() {
+		return f, nil
+	}
+
+	if r.From == nil || r.To == nil || r.To.Before(*r.From) {
+		return f, errors.New("either orderNumbers, orderIds, states or a from-to window is required")
+	}
+	f.From, f.To = r.From.UTC(), r.To.UTC()
+	if !f.IncludeCreated && !f.IncludeUpdated && !f.IncludeDeliveryUpdated && !f.IncludeTransactionUpdated {
+		f.IncludeCreated, f.IncludeUpdated, f.IncludeDeliveryUpdated, f.IncludeTransactionUpdated = true, true, true, true
+	}
+	return f, nil
+}
+
+type scan struct {
+	id        string
+	request   scanRequest
+	startedAt time.Time
+
+	mu         sync.Mutex
+	status     string            // guarded by mu
+	finishedAt time.Time         // guarded by mu
+	result     orders.ScanResult // guarded by mu
+	err        error             // guarded by mu
+}
+
+func (s *scan) finish(result orders.ScanResult, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.finishedAt = time.Now()
+	s.result, s.err = result, err
+	s.status = statusSucceeded
+	if err != nil {
+		s.status = statusFailed
+	}
+}
+
+func (s *scan) running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status == statusRunning
+}
+
+type scanView struct {
+	ID         string      `json:"id"`
+	Status     string      `json:"status"`
+	Request    scanRequest `json:"request"`
+	StartedAt  time.Time   `json:"startedAt"`
+	FinishedAt *time.Time  `json:"finishedAt,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Scanned    int         `json:"scanned"`
+	Orders     []orderView `json:"orders"`
+}
+
+// orderView is a suspicious order with messages of the failed checks by rule.
+type orderView struct {
+	ID           string            `json:"id"`
+	Number       string            `json:"number"`
+	ChannelID    string            `json:"channelId"`
+	TrackingCode string            `json:"trackingCode,omitempty"`
+	CreatedDate  string            `json:"createdDate"`
+	Failures     map[string]string `json:"failures"`
+}
+
+func (s *scan) view() scanView {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v := scanView{
+		ID:        s.id,
+		Status:    s.status,
+		Request:   s.request,
+		StartedAt: s.startedAt,
+		Scanned:   s.result.Scanned,
+		Orders:    []orderView{},
+	}
+	if !s.finishedAt.IsZero() {
+		finishedAt := s.finishedAt
+		v.FinishedAt = &finishedAt
+	}
+	if s.err != nil {
+		v.Error = s.err.Error()
+	}
+
+	for _, o := range s.result.Orders {
+		failures := map[string]string{}
+		for rule, err := range o.Errors {
+			failures[rule] = err.Error()
+		}
+		v.Orders = append(v.Orders, orderView{
+			ID:           o.OrderID,
+			Number:       o.OrderNumber,
+			ChannelID:    o.ChannelID,
+			TrackingCode: o.TrackingCode,
+			CreatedDate:  o.CreatedDate,
+			Failures:     failures,
+		})
+	}
+	return v
+}
+
+type evaluationView struct {
+	ID       string            `json:"id"`
+	Number   string            `json:"number"`
+	State    domain.OrderState `json:"state"`
+	Outcomes []outcomeView     `json:"outcomes"`
+}
+
+type outcomeView struct {
+	Rule        string        `json:"rule"`
+	Description string        `json:"description"`
+	Status      checks.Status `json:"status"`
+	Reason      string        `json:"reason,omitempty"`
+	Error       string        `json:"error,omitempty"`
+}
+
+func toEvaluationView(e orders.Evaluation) evaluationView {
+	v := evaluationView{
+		ID:       e.Order.ID,
+		Number:   e.Order.Number,
+		State:    e.Order.State,
+		Outcomes: []outcomeView{},
+	}
+	for _, o := range e.Outcomes {
+		ov := outcomeView{
+			Rule:        o.Rule,
+			Description: o.Description,
+			Status:      o.Status,
+			Reason:      o.Reason,
+		}
+		if o.Err != nil {
+			ov.Error = o.Err.Error()
+		}
+		v.Outcomes = append(v.Outcomes, ov)
+	}
+	return v
+}
@@ -0,0 +1,210 @@
+// Package api lets internal tools trigger scans and evaluate orders over HTTP.
+//
+//	POST /scans                  starts a scan of the orders selected by the JSON body, see scanRequest
+//	GET  /scans/{id}             returns status and, once succeeded, suspicious orders of the scan
+//	GET  /orders/{number}/checks returns outcomes of all checks for the order
+//
+// All requests must carry the "Authorization: Bearer <token>" header.
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/orders"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxScans is the number of scans kept in memory, the oldest finished ones are forgotten first.
+	maxScans = 100
+	// maxRunningScans is the number of scans running at once, more are rejected rather than queued.
+	maxRunningScans = 2
+	// maxBodySize limits the JSON body of a scan request.
+	maxBodySize = 1 << 20
+)
+
+type handler struct {
+	ctx     context.Context
+	service orders.Service
+	token   string
+	running chan struct{} // a slot per running scan
+
+	mu    sync.Mutex
+	scans map[string]*scan // guarded by mu
+	ids   []string         // guarded by mu, in order of start
+}
+
+// NewHandler serves the API, scans run in the background until they finish or ctx is done.
+func NewHandler(ctx context.Context, service orders.Service, token string) http.Handler {
+	h := &handler{
+		ctx:     ctx,
+		service: service,
+		token:   token,
+		running: make(chan struct{}, maxRunningScans),
+		scans:   map[string]*scan{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/scans", h.authorized(h.startScan))
+	mux.HandleFunc("/scans/", h.authorized(h.getScan))
+	mux.HandleFunc("/orders/", h.authorized(h.orderChecks))
+	return mux
+}
+
+func (h *handler) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, "Bearer ")), []byte(h.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (h *handler) startScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req scanRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter, err := req.filter()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	select {
+	case h.running <- struct{}{}:
+	default:
+		writeError(w, http.StatusTooManyRequests, fmt.Sprintf("%d scans are running already", maxRunningScans))
+		return
+	}
+
+	id, err := newID()
+	if err != nil {
+		<-h.running
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s := &scan{id: id, request: req, status: statusRunning, startedAt: time.Now()}
+	h.add(s)
+	go h.run(s, filter)
+
+	w.Header().Set("Location", "/scans/"+id)
+	writeJSON(w, http.StatusAccepted, s.view())
+}
+
+func (h *handler) run(s *scan, filter sources.FilterRequest) {
+	defer func() { <-h.running }()
+
+	log := zap.L().With(zap.String("run", "api"), zap.String("scan_id", s.id))
+	log.Info("starting API scan")
+	result, err := h.service.ScanOrders(h.ctx, filter)
+	s.finish(result, err)
+	if err != nil {
+		log.Error("API scan has failed", zap.Error(err))
+		return
+	}
+	log.Info("detected suspicious orders", zap.Int("suspicious", len(result.Orders)), zap.Int("scanned", result.Scanned))
+}
+
+func (h *handler) getScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/scans/")
+	h.mu.Lock()
+	s, ok := h.scans[id]
+	h.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("scan [%s] not found", id))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.view())
+}
+
+// orderChecks evaluates the order right away, GET /orders/{number}/checks.
+func (h *handler) orderChecks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/orders/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "checks" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	number := parts[0]
+
+	evaluations, err := h.service.EvaluateOrders(r.Context(), sources.FilterRequest{OrderNumbers: []string{number}})
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("failed to evaluate order [%s] : %v", number, err))
+		return
+	}
+	if len(evaluations) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("order [%s] not found", number))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toEvaluationView(evaluations[0]))
+}
+
+// add keeps at most maxScans scans, forgetting the oldest finished ones.
+func (h *handler) add(s *scan) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.scans[s.id] = s
+	h.ids = append(h.ids, s.id)
+
+	for i := 0; len(h.ids) > maxScans && i < len(h.ids); {
+		id := h.ids[i]
+		if h.scans[id].running() {
+			i++
+			continue
+		}
+		delete(h.scans, id)
+		h.ids = append(h.ids[:i], h.ids[i+1:]...)
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("rand.Read : %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		zap.L().Error("failed to write response", zap.Int("status", status), zap.Error(err))
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,230 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/api"
+	"github.com/nikolayk812/shopware-orders-scanner/checks"
+	"github.com/nikolayk812/shopware-orders-scanner/orders"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	token = "secret-token"
+	// slow is an order number the source holds a lookup of until the test finishes.
+	slow = "slow"
+)
+
+// source records requests and returns no orders, lookups of slow ones block until released.
+type source struct {
+	release chan struct{}
+
+	mu       sync.Mutex
+	requests []sources.FilterRequest
+}
+
+func (s *source) Fetch(ctx context.Context, req sources.FilterRequest, _ chan<- sources.Page) error {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	if len(req.OrderNumbers) > 0 && req.OrderNumbers[0] == slow {
+		select {
+		case <-s.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (s *source) lastRequest() sources.FilterRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[len(s.requests)-1]
+}
+
+func newServer(t *testing.T) (*httptest.Server, *source) {
+	t.Helper()
+
+	src := &source{release: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	service := orders.NewService(src, checks.NewEngine(map[string]checks.Check{}), 1)
+	srv := httptest.NewServer(api.NewHandler(ctx, service, token))
+	t.Cleanup(func() {
+		close(src.release)
+		cancel()
+		srv.Close()
+	})
+	return srv, src
+}
+
+type scanView struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// do sends the request with the token and decodes a JSON response into v unless it is nil.
+func do(t *testing.T, method, url, body string, v interface{}) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("http.NewRequest: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("http.Do: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("json.Decode: %v", err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func startScan(t *testing.T, srv *httptest.Server, body string) string {
+	t.Helper()
+
+	var view scanView
+	if status := do(t, http.MethodPost, srv.URL+"/scans", body, &view); status != http.StatusAccepted {
+		t.Fatalf("POST /scans [%s] : got status %d, want %d", body, status, http.StatusAccepted)
+	}
+	return view.ID
+}
+
+func waitFinished(t *testing.T, srv *httptest.Server, id string) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		var view scanView
+		if status := do(t, http.MethodGet, srv.URL+"/scans/"+id, "", &view); status != http.StatusOK {
+			t.Fatalf("GET /scans/%s : got status %d, want %d", id, status, http.StatusOK)
+		}
+		if view.Status != "running" {
+			return
+		}
+	}
+	t.Fatalf("scan [%s] has not finished in time", id)
+}
+
+func TestHandler_Unauthorized(t *testing.T) {
+	srv, _ := newServer(t)
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"missing header", ""},
+		{"token without prefix", token},
+		{"basic scheme", "Basic " + token},
+		{"lower case prefix", "bearer " + token},
+		{"wrong token", "Bearer wrong-token"},
+		{"empty token", "Bearer "},
+	}
+	for _, tt := range tests {
+		for _, path := range []string{"/scans", "/scans/1", "/orders/10001/checks"} {
+			t.Run(tt.name+" "+path, func(t *testing.T) {
+				req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+				if err != nil {
+					t.Fatalf("http.NewRequest: %v", err)
+				}
+				if tt.header != "" {
+					req.Header.Set("Authorization", tt.header)
+				}
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatalf("http.Do: %v", err)
+				}
+				resp.Body.Close()
+
+				if resp.StatusCode != http.StatusUnauthorized {
+					t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+				}
+			})
+		}
+	}
+}
+
+func TestHandler_RunningScansLimit(t *testing.T) {
+	srv, _ := newServer(t)
+	body := fmt.Sprintf(`{"orderNumbers": [%q]}`, slow)
+
+	startScan(t, srv, body)
+	startScan(t, srv, body)
+
+	var resp map[string]string
+	if status := do(t, http.MethodPost, srv.URL+"/scans", body, &resp); status != http.StatusTooManyRequests {
+		t.Fatalf("got status %d, want %d", status, http.StatusTooManyRequests)
+	}
+	if want := "2 scans are running already"; resp["error"] != want {
+		t.Errorf("got error [%s], want [%s]", resp["error"], want)
+	}
+}
+
+// TestHandler_Eviction keeps the running scan while forgetting the oldest finished one.
+func TestHandler_Eviction(t *testing.T) {
+	srv, _ := newServer(t)
+
+	running := startScan(t, srv, fmt.Sprintf(`{"orderNumbers": [%q]}`, slow))
+	var ids []string
+	for i := 0; i < 100; i++ {
+		id := startScan(t, srv, `{"orderNumbers": ["10001"]}`)
+		waitFinished(t, srv, id)
+		ids = append(ids, id)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		want int
+	}{
+		{"running", running, http.StatusOK},
+		{"oldest finished", ids[0], http.StatusNotFound},
+		{"second oldest finished", ids[1], http.StatusOK},
+		{"latest", ids[len(ids)-1], http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := do(t, http.MethodGet, srv.URL+"/scans/"+tt.id, "", nil); status != tt.want {
+				t.Errorf("got status %d, want %d", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_WindowInUTC(t *testing.T) {
+	srv, src := newServer(t)
+
+	id := startScan(t, srv, `{"from": "2020-10-01T02:00:00+02:00", "to": "2020-10-02T02:00:00+02:00"}`)
+	waitFinished(t, srv, id)
+
+	req := src.lastRequest()
+	wantFrom := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	if req.From != wantFrom || req.To != wantFrom.AddDate(0, 0, 1) {
+		t.Errorf("got window [%v, %v], want [%v, %v]", req.From, req.To, wantFrom, wantFrom.AddDate(0, 0, 1))
+	}
+	if !req.IncludeCreated || !req.IncludeUpdated || !req.IncludeDeliveryUpdated || !req.IncludeTransactionUpdated {
+		t.Errorf("got %+v, want all kinds of changes included", req)
+	}
+}
+
+func TestHandler_BodyTooLarge(t *testing.T) {
+	srv, _ := newServer(t)
+
+	body := `{"orderNumbers": ["` + strings.Repeat("1", 2<<20) + `"]}`
+	if status := do(t, http.MethodPost, srv.URL+"/scans", body, nil); status != http.StatusBadRequest {
+		t.Errorf("got status %d, want %d", status, http.StatusBadRequest)
+	}
+}
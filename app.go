@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/go-resty/resty/v2"
 	"github.com/nikolayk812/shopware-orders-scanner/checks"
@@ -10,48 +12,236 @@ import (
 	"github.com/nikolayk812/shopware-orders-scanner/config"
 	"github.com/nikolayk812/shopware-orders-scanner/consumers/html"
 	"github.com/nikolayk812/shopware-orders-scanner/consumers/mail"
+	"github.com/nikolayk812/shopware-orders-scanner/dryrun"
+	"github.com/nikolayk812/shopware-orders-scanner/findings"
+	"github.com/nikolayk812/shopware-orders-scanner/metrics"
 	"github.com/nikolayk812/shopware-orders-scanner/orders"
+	"github.com/nikolayk812/shopware-orders-scanner/snapshot"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	swsource "github.com/nikolayk812/shopware-orders-scanner/sources/shopware"
+	"github.com/nikolayk812/shopware-orders-scanner/tracing"
+	"github.com/nikolayk812/shopware-orders-scanner/watermark"
+	"go.opentelemetry.io/otel/label"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"io/ioutil"
 	"log"
+	"os"
+	"strings"
 	"time"
 )
 
+// templates read on every report, relative to the working directory
+const (
+	reportTemplate    = "./consumers/html/template.twig"
+	shopsTemplate     = "./consumers/html/shops.twig"
+	dashboardTemplate = "./dashboard/template.html"
+)
+
 type mainConfig struct {
-	config.Shopware
-	config.SendGrid
+	config.Scan
+	config.Findings
+	Profile config.Profile `ignored:"true"`
+}
+
+// commands by name, scan is the default one
+var commands = map[string]func(args []string) error{
+	"scan":    pushed(runScan),
+	"recheck": runRecheck,
+	"explain": runExplain,
+	"sweep":   pushed(runSweep),
+	"serve":   runServe,
+	"config":  runConfig,
 }
 
 func main() {
 	logger, err := buildLogger()
 	if err != nil {
-		log.Fatalf("buildLogger: %v", err)
+		log.Printf("buildLogger: %v", err)
+		os.Exit(exitConfig)
 	}
-	defer logger.Sync()
 	zap.ReplaceGlobals(logger)
 
-	now := time.Now()
+	code := run(os.Args[1:])
+	logger.Sync()
+	os.Exit(code)
+}
+
+// run runs the command named by the first argument, scan by default, and returns the exit code.
+// Global flags precede the command name.
+func run(args []string) int {
+	global := flag.NewFlagSet("shopware-orders-scanner", flag.ContinueOnError)
+	dryRunFlag := global.Bool("dry-run", false, "log writes instead of performing them and dump notifications into DRY_RUN_DIR instead of sending them")
+	globalArgs, args := splitGlobal(global, args)
+	if err := global.Parse(globalArgs); err != nil {
+		return exitCode(configError(err))
+	}
+
+	name := "scan"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	command, ok := commands[name]
+	if !ok {
+		zap.L().Error("unknown command", zap.String("command", name))
+		return exitConfig
+	}
+
 	zap.S().Info("starting Shopware orders scanner")
-	defer zap.S().Infof("stopping Shopware orders scanner")
+	defer zap.S().Info("stopping Shopware orders scanner")
+
+	if err := initDryRun(*dryRunFlag); err != nil {
+		zap.L().Error("initDryRun has failed", zap.Error(err))
+		return exitCode(err)
+	}
+
+	shutdownTracing, err := initTracing()
+	if err != nil {
+		zap.L().Error("initTracing has failed", zap.Error(err))
+		return exitCode(err)
+	}
+
+	err = command(args)
+	if shutdownErr := shutdownTracing(context.Background()); shutdownErr != nil {
+		zap.L().Error("failed to shut down tracing", zap.Error(shutdownErr))
+	}
+
+	code := exitCode(err)
+	switch code {
+	case exitClean:
+	case exitFindings:
+		zap.L().Warn("command has detected findings", zap.String("command", name), zap.Error(err))
+	default:
+		zap.L().Error("command has failed", zap.String("command", name), zap.Int("exit_code", code), zap.Error(err))
+	}
+	return code
+}
+
+// splitGlobal splits leading flags defined by global, which are all bool ones, from the command and its arguments.
+func splitGlobal(global *flag.FlagSet, args []string) ([]string, []string) {
+	for i, arg := range args {
+		name := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+		if !strings.HasPrefix(arg, "-") || global.Lookup(name) == nil {
+			return args[:i], args[i:]
+		}
+	}
+	return args, nil
+}
+
+// initDryRun enables the dry run by the -dry-run flag or DRY_RUN variable.
+func initDryRun(dryRunFlag bool) error {
+	var cfg config.DryRun
+	if err := config.Parse("local.env", &cfg); err != nil {
+		return configError(fmt.Errorf("config.Parse: %w", err))
+	}
+	if !dryRunFlag && !cfg.Enabled {
+		return nil
+	}
+	dryrun.Enable(cfg.Dir)
+	zap.S().Warnw("dry run, writes are logged and notifications dumped", "dir", cfg.Dir)
+	return nil
+}
+
+func initTracing() (func(context.Context) error, error) {
+	var cfg config.Tracing
+	if err := config.Parse("local.env", &cfg); err != nil {
+		return nil, configError(fmt.Errorf("config.Parse: %w", err))
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, configError(err)
+	}
+	return tracing.Init(cfg)
+}
+
+// runScan checks orders created or updated since the last successful scan, or only the given orders.
+func runScan(args []string) error {
+	flags := flag.NewFlagSet("scan", flag.ContinueOnError)
+	snapshotPath := flags.String("snapshot", "", "write scanned orders to this NDJSON file for the recheck command")
+	var orderNumbers stringsFlag
+	flags.Var(&orderNumbers, "order", "check only the order with this number and print outcomes of all checks, repeatable")
+	idsFile := flags.String("ids-file", "", "check only orders with ids listed in this file, one per line, and print outcomes of all checks")
+	pf := addProfileFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return configError(err)
+	}
+
+	var orderIDs []string
+	if *idsFile != "" {
+		ids, err := readLines(*idsFile)
+		if err != nil {
+			return configError(fmt.Errorf("readLines : %w", err))
+		}
+		orderIDs = ids
+	}
 
 	var cfg mainConfig
 	if err := config.Parse("local.env", &cfg); err != nil {
-		log.Fatalf("config.Parse: %v", err)
+		return configError(fmt.Errorf("config.Parse: %w", err))
+	}
+	if err := validateExit(cfg.Scan); err != nil {
+		return err
+	}
+	shops, err := pf.load()
+	if err != nil {
+		return err
+	}
+
+	lookup := len(orderNumbers) > 0 || len(orderIDs) > 0
+	if len(shops.Profiles) > 1 && (lookup || *snapshotPath != "") {
+		return configError(errors.New("-order, -ids-file and -snapshot need a single profile, select it by -profile"))
 	}
 
-	orderCli, _, err := buildShopwareClients(cfg.Shopware)
+	if !lookup {
+		if err := validateTemplates(); err != nil {
+			return err
+		}
+		runs, err := runShops(context.Background(), cfg, shops, *snapshotPath, scan)
+		return checkShops(cfg.Scan, runs, err)
+	}
+
+	cfg.Profile = shops.Profiles[0]
+	service, closeService, err := buildService(cfg, *snapshotPath)
 	if err != nil {
-		log.Fatalf("buildShopwareClients : %v", err)
+		return fmt.Errorf("buildService : %w", err)
 	}
+	defer closeService()
 
-	midNight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
-	from := midNight.AddDate(0, 0, -1)
-	to := midNight.Add(-time.Nanosecond)
+	req := sources.FilterRequest{OrderIDs: orderIDs, OrderNumbers: orderNumbers}
+	evaluations, err := service.EvaluateOrders(context.Background(), req)
+	if err != nil {
+		return sourceError(fmt.Errorf("failed to evaluate orders : %w", err))
+	}
+	if err := printEvaluations(os.Stdout, req, evaluations); err != nil {
+		return fmt.Errorf("printEvaluations : %w", err)
+	}
+	return checkFindings(cfg.Scan, orders.EvaluationsResult(evaluations))
+}
+
+// scan checks orders created or updated since the last successful scan, reports suspicious ones and advances the watermark.
+func scan(ctx context.Context, cfg mainConfig, snapshotPath string) (_ orders.ScanResult, err error) {
+	now, shop := time.Now(), cfg.Profile.Shopware.BaseURL
+	ctx, span := tracing.Start(ctx, "scan", label.String("shop", shop))
+	defer func() {
+		metrics.ObserveRun("scan", shop, now, err)
+		tracing.End(ctx, span, err)
+	}()
+
+	service, closeService, err := buildService(cfg, snapshotPath)
+	if err != nil {
+		return orders.ScanResult{}, fmt.Errorf("buildService : %w", err)
+	}
+	defer closeService()
+	if service, err = watchFindings(cfg, service); err != nil {
+		return orders.ScanResult{}, err
+	}
 
-	engine := buildEngine()
-	service := orders.NewService(orderCli, engine)
-	badOrders, scanned, err := service.ScanOrders(context.Background(), orders.FilterRequest{
+	store := watermark.NewStore(cfg.Scan.WatermarkDir)
+	from, to, err := scanWindow(store, shop, now, cfg.Scan.Overlap)
+	if err != nil {
+		return orders.ScanResult{}, fmt.Errorf("scanWindow : %w", err)
+	}
+
+	result, err := service.ScanOrders(ctx, sources.FilterRequest{
 		From:                      from,
 		To:                        to,
 		IncludeCreated:            true,
@@ -60,66 +250,269 @@ func main() {
 		IncludeTransactionUpdated: true,
 	})
 	if err != nil {
-		log.Fatalf("failed to scan yesterday orders : %v", err)
+		return orders.ScanResult{}, sourceError(fmt.Errorf("failed to scan orders from [%s] to [%s] : %w", from, to, err))
+	}
+	zap.L().Info("detected suspicious orders", zap.String("run", "scan"), zap.String("shop", shop), zap.Int("suspicious", len(result.Orders)),
+		zap.Int("scanned", result.Scanned), zap.Int("pages", result.Pages), zap.Int("rows", result.Rows))
+	metrics.ObserveResult("scan", shop, result, buildEngine(cfg.Profile.Checks).Rules())
+
+	if err := report(ctx, cfg, now, result); err != nil {
+		// still part of a combined report of several shops
+		return result, err
+	}
+
+	// advanced only after the report is out, otherwise the next scan repeats this window
+	if err := store.Save(shop, to); err != nil {
+		return orders.ScanResult{}, fmt.Errorf("store.Save : %w", err)
 	}
-	zap.S().Infof("detected %d suspicious orders", len(badOrders))
+	return result, nil
+}
 
-	if cfg.SendGrid.Enabled {
-		sender := mail.NewSender(cfg.Shopware, cfg.SendGrid)
-		_, err = sender.Consume(badOrders, scanned)
+// scanWindow continues from the watermark of the last successful scan minus overlap up to now,
+// the first scan of a shop covers yesterday and today so far.
+func scanWindow(store watermark.Store, shop string, now time.Time, overlap time.Duration) (time.Time, time.Time, error) {
+	now = now.UTC()
+
+	scannedUntil, err := store.Load(shop)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("store.Load : %w", err)
+	}
+
+	if scannedUntil.IsZero() {
+		midNight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		from := midNight.AddDate(0, 0, -1)
+		zap.S().Infow("no watermark, scanning since yesterday", "shop", shop, "from", from, "to", now)
+		return from, now, nil
+	}
+
+	from := scannedUntil.Add(-overlap)
+	zap.S().Infow("scanning since watermark minus overlap", "shop", shop, "from", from, "to", now,
+		"watermark", scannedUntil, "overlap", overlap)
+	return from, now, nil
+}
+
+// buildService scans the shop of the profile, writing scanned orders into a snapshot file unless snapshotPath is empty.
+// The returned func stops refreshing the access token and closes the snapshot.
+func buildService(cfg mainConfig, snapshotPath string) (orders.Service, func(), error) {
+	engine := buildEngine(cfg.Profile.Checks)
+	includes := swsource.Includes(append(engine.Fields(), orders.Fields...))
+	if snapshotPath != "" {
+		// rechecks may need fields the current checks don't read
+		includes = nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	orderCli, _, err := buildShopwareClients(ctx, cfg.Profile.Shopware, includes)
+	if err != nil {
+		cancel()
+		return orders.Service{}, nil, fmt.Errorf("buildShopwareClients : %w", err)
+	}
+
+	source := swsource.NewSource(orderCli, cfg.Scan.Parallelism)
+	service := orders.NewService(source, engine, cfg.Scan.Parallelism)
+	if snapshotPath == "" {
+		return service, cancel, nil
+	}
+
+	w, err := snapshot.NewWriter(snapshotPath)
+	if err != nil {
+		cancel()
+		return orders.Service{}, nil, fmt.Errorf("snapshot.NewWriter : %w", err)
+	}
+	closeService := func() {
+		cancel()
+		if err := w.Close(); err != nil {
+			zap.L().Error("failed to close snapshot", zap.String("path", snapshotPath), zap.Error(err))
+		}
+	}
+	return service.WithRecorder(w), closeService, nil
+}
+
+// watchFindings makes the service report rechecks of orders with open findings, so report can resolve them.
+func watchFindings(cfg mainConfig, service orders.Service) (orders.Service, error) {
+	ids, err := findings.NewStore(cfg.Findings.Path).OpenOrderIDs(cfg.Profile.Shopware.BaseURL)
+	if err != nil {
+		return orders.Service{}, fmt.Errorf("store.OpenOrderIDs : %w", err)
+	}
+	return service.WithWatched(ids), nil
+}
+
+// report consumes the result and records its findings for the dashboard.
+func report(ctx context.Context, cfg mainConfig, at time.Time, result orders.ScanResult) error {
+	if err := consume(ctx, cfg.Profile, result); err != nil {
+		return consumerError(err)
+	}
+
+	store := findings.NewStore(cfg.Findings.Path)
+	recordCtx, span := tracing.Start(ctx, "findings.Record")
+	err := store.Record(cfg.Profile.Shopware.BaseURL, at, result, buildEngine(nil).Severities())
+	tracing.End(recordCtx, span, err)
+	if err != nil {
+		return fmt.Errorf("store.Record : %w", err)
+	}
+
+	all, err := store.All()
+	if err != nil {
+		return fmt.Errorf("store.All : %w", err)
+	}
+	open := map[string]int{}
+	for _, severity := range []checks.Severity{checks.SeverityHigh, checks.SeverityMedium, checks.SeverityLow} {
+		open[string(severity)] = 0
+	}
+	for _, f := range all {
+		if f.Open() {
+			open[string(f.Severity)]++
+		}
+	}
+	metrics.SetOpenFindings(open)
+	return nil
+}
+
+// pushed pushes metrics to the Pushgateway once the command has returned, if a Pushgateway is configured.
+func pushed(command func(args []string) error) func(args []string) error {
+	return func(args []string) error {
+		err := command(args)
+
+		var metricsCfg config.Metrics
+		if cfgErr := config.Parse("local.env", &metricsCfg); cfgErr != nil {
+			zap.L().Error("failed to parse metrics config", zap.Error(cfgErr))
+			return err
+		}
+		if metricsCfg.PushgatewayURL == "" {
+			return err
+		}
+		if pushErr := metrics.Push(metricsCfg.PushgatewayURL, metricsCfg.Job); pushErr != nil {
+			zap.L().Error("failed to push metrics", zap.String("url", metricsCfg.PushgatewayURL), zap.Error(pushErr))
+		}
+		return err
+	}
+}
+
+// consume sends the report to consumers of the profile, by email if enabled, otherwise into reports directory by default.
+func consume(ctx context.Context, profile config.Profile, result orders.ScanResult) error {
+	consumers := profile.Consumers
+	if len(consumers) == 0 {
+		consumers = []string{consumerHTML}
+		if profile.SendGrid.Enabled {
+			consumers = []string{consumerMail}
+		}
+	}
+
+	for _, c := range consumers {
+		var err error
+		switch c {
+		case consumerMail:
+			err = consumeMail(ctx, profile, result)
+		case consumerHTML:
+			err = consumeHTML(ctx, profile, result)
+		default:
+			err = fmt.Errorf("unknown consumer [%s]", c)
+		}
 		if err != nil {
-			log.Fatalf("sender.Consume : %v", err)
+			return err
 		}
-		return
 	}
+	return nil
+}
+
+func consumeMail(ctx context.Context, profile config.Profile, result orders.ScanResult) (err error) {
+	ctx, span := tracing.Start(ctx, "mail.Consume", label.Int("orders", len(result.Orders)))
+	defer func() { tracing.End(ctx, span, err) }()
+
+	sender := mail.NewSender(profile)
+	if _, err := sender.Consume(result.Orders, result.Scanned); err != nil {
+		return fmt.Errorf("sender.Consume : %w", err)
+	}
+	return nil
+}
 
-	htmlRenderer := html.NewRenderer("./consumers/html/template.twig", cfg.Shopware.BaseURL)
-	document, err := htmlRenderer.Consume(badOrders, scanned)
+func consumeHTML(ctx context.Context, profile config.Profile, result orders.ScanResult) (err error) {
+	ctx, span := tracing.Start(ctx, "html.Consume", label.Int("orders", len(result.Orders)))
+	defer func() { tracing.End(ctx, span, err) }()
+
+	htmlRenderer := html.NewRenderer(reportTemplate, profile.Shopware.BaseURL, profile.SalesChannels)
+	document, err := htmlRenderer.Consume(result.Orders, result.Scanned)
 	if err != nil {
-		log.Fatalf("htmlRenderer.Consume : %v", err)
+		return fmt.Errorf("htmlRenderer.Consume : %w", err)
+	}
+	name := "report_"
+	if profile.Name != "" {
+		name += profile.Name + "_"
 	}
-	fileName := "./reports/" + time.Now().Format("report_01-02-2006_15:04") + ".html"
+	fileName := "./reports/" + name + time.Now().Format("01-02-2006_15:04") + ".html"
 	if err := ioutil.WriteFile(fileName, document.Bytes, 0644); err != nil {
-		log.Fatalf("WriteFile : %v", err)
+		return fmt.Errorf("WriteFile : %w", err)
 	}
+	return nil
+}
+
+// rules by name, profiles may enable a subset of them
+var rules = map[string]checks.Check{
+	"TRACKING_CODE":       common.ShippedTrackingCode{},
+	"PDF_DOCUMENT":        common.ShippedPdfDocument{},
+	"RETURN_REFUND_STATE": common.ReturnedRefundedState{},
+	"DONE_SHIPPED":        common.DoneDeliveryNotOpen{},
 }
 
-func buildEngine() checks.Engine {
-	rr := map[string]checks.Check{
-		"TRACKING_CODE":       common.ShippedTrackingCode{},
-		"PDF_DOCUMENT":        common.ShippedPdfDocument{},
-		"RETURN_REFUND_STATE": common.ReturnedRefundedState{},
-		"DONE_SHIPPED":        common.DoneDeliveryNotOpen{},
+// buildEngine applies the enabled rules, all if none, their names are validated with the profile.
+func buildEngine(enabled []string) checks.Engine {
+	if len(enabled) == 0 {
+		return checks.NewEngine(rules)
 	}
 
+	rr := map[string]checks.Check{}
+	for _, name := range enabled {
+		if rule, ok := rules[name]; ok {
+			rr[name] = rule
+		}
+	}
 	return checks.NewEngine(rr)
 }
 
-func buildShopwareClients(conf config.Shopware) (shopware.OrderService, shopware.ProductService, error) {
-	httpCli := resty.New().SetHostURL(conf.BaseURL)
+// buildShopwareClients builds clients of a shop, their access token is refreshed until ctx is done.
+func buildShopwareClients(ctx context.Context, conf config.Shopware, includes shopware.Includes) (shopware.OrderService, shopware.ProductService, error) {
+	// skipped writes of a dry run never reach the shop, so they aren't instrumented either
+	httpCli := resty.New().SetHostURL(conf.BaseURL).SetTransport(dryrun.Transport(metrics.Transport(nil), shopware.IsWrite))
 
-	tokenProvider, err := shopware.NewCredTokenProvider(httpCli, conf.ClientID, conf.ClientSecret)
+	tokenProvider, err := shopware.NewCredTokenProvider(ctx, httpCli, conf.ClientID, string(conf.ClientSecret))
 	if err != nil {
-		return nil, nil, fmt.Errorf("shopware.NewCredTokenProvider: %w", err)
+		return nil, nil, sourceError(fmt.Errorf("shopware.NewCredTokenProvider: %w", err))
 	}
 
-	return shopware.NewOrderService(httpCli, tokenProvider),
+	return shopware.NewOrderService(httpCli, tokenProvider, includes),
 		shopware.NewProductService(httpCli, tokenProvider), nil
 }
 
+// buildLogger builds a logger by the LOG_* variables, JSON lines keep structured fields indexable by log pipelines.
 func buildLogger() (*zap.Logger, error) {
+	var cfg config.Log
+	if err := config.Parse("local.env", &cfg); err != nil {
+		return nil, fmt.Errorf("config.Parse: %w", err)
+	}
+
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL [%s] : %w", cfg.Level, err)
+	}
+
 	ec := zap.NewProductionEncoderConfig()
-	ec.LevelKey = "l"
-	ec.EncodeLevel = zapcore.CapitalLevelEncoder
 	ec.EncodeTime = zapcore.RFC3339TimeEncoder
 	ec.CallerKey = zapcore.OmitKey
 	ec.StacktraceKey = zapcore.OmitKey
+	switch cfg.Format {
+	case "console":
+		ec.LevelKey = "l"
+		ec.EncodeLevel = zapcore.CapitalLevelEncoder
+	case "json":
+	default:
+		return nil, fmt.Errorf("invalid LOG_FORMAT [%s], expected console or json", cfg.Format)
+	}
 
 	return zap.Config{
 		EncoderConfig:    ec,
-		Encoding:         "console",
-		Level:            zap.NewAtomicLevelAt(zapcore.InfoLevel),
-		OutputPaths:      []string{"stdout"},
+		Encoding:         cfg.Format,
+		Level:            zap.NewAtomicLevelAt(level),
+		OutputPaths:      []string{cfg.Output},
 		ErrorOutputPaths: []string{"stderr"},
 	}.Build()
 }
@@ -1,10 +1,56 @@
 package checks
 
-import "github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
+import (
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+)
 
 type Check interface {
 	//(true, nil) -> okay
-	//(false, nil) -> skipped, i.e. not applicable
+	//(false, nil) or (false, Skip(reason)) -> skipped, i.e. not applicable
 	//(false, err) -> failure
-	Apply(order shopware.Order) (bool, error)
+	Apply(order domain.Order) (bool, error)
+
+	// Fields lists the order fields Apply reads, sources may fetch only those.
+	Fields() []domain.Field
+
+	// Description states what the check expects from applicable orders.
+	Description() string
+
+	// Severity ranks failures of the check against failures of other checks.
+	Severity() Severity
+}
+
+type Severity string
+
+const (
+	SeverityLow    Severity = "low"
+	SeverityMedium Severity = "medium"
+	SeverityHigh   Severity = "high"
+)
+
+var severityRanks = map[Severity]int{SeverityLow: 1, SeverityMedium: 2, SeverityHigh: 3}
+
+// Valid reports whether s is one of the declared severities.
+func (s Severity) Valid() bool {
+	return severityRanks[s] > 0
+}
+
+// AtLeast reports whether s is as severe as min or more.
+func (s Severity) AtLeast(min Severity) bool {
+	return severityRanks[s] >= severityRanks[min]
+}
+
+// SkipError explains which pre-condition of a check an order doesn't meet.
+type SkipError struct {
+	Reason string
+}
+
+func (e SkipError) Error() string {
+	return "skipped: " + e.Reason
+}
+
+// Skip is returned by checks not applicable to an order.
+func Skip(format string, args ...interface{}) error {
+	return SkipError{Reason: fmt.Sprintf(format, args...)}
 }
@@ -0,0 +1,111 @@
+package common_test
+
+import (
+	"github.com/nikolayk812/shopware-orders-scanner/checks"
+	"github.com/nikolayk812/shopware-orders-scanner/checks/common"
+	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
+	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware/shopwaretest"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	swsource "github.com/nikolayk812/shopware-orders-scanner/sources/shopware"
+	"testing"
+	"time"
+)
+
+func toDomain(t *testing.T, b *shopwaretest.OrderBuilder) domain.Order {
+	t.Helper()
+	order, err := swsource.ToDomain(b.Build())
+	if err != nil {
+		t.Fatalf("swsource.ToDomain: %v", err)
+	}
+	return order
+}
+
+type checkTest struct {
+	name  string
+	order *shopwaretest.OrderBuilder
+	want  checks.Status
+}
+
+func runCheckTests(t *testing.T, check checks.Check, tests []checkTest) {
+	t.Helper()
+	engine := checks.NewEngine(map[string]checks.Check{"CHECK": check})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome := engine.Evaluate(toDomain(t, tt.order))[0]
+			if outcome.Status != tt.want {
+				t.Errorf("got %s with error [%v] and reason [%s], want %s", outcome.Status, outcome.Err, outcome.Reason, tt.want)
+			}
+			if outcome.Status == checks.StatusSkipped && outcome.Reason == "" {
+				t.Errorf("got no reason of the skip")
+			}
+		})
+	}
+}
+
+func order() *shopwaretest.OrderBuilder {
+	return shopwaretest.NewOrder(shopwaretest.ID(1))
+}
+
+func delivery(state shopware.OrderDeliveryState) *shopwaretest.DeliveryBuilder {
+	return shopwaretest.NewDelivery(state)
+}
+
+func TestShippedTrackingCode(t *testing.T) {
+	runCheckTests(t, common.ShippedTrackingCode{}, []checkTest{
+		{"no deliveries", order(), checks.StatusSkipped},
+		{"open delivery", order().Delivery(delivery(shopware.OrderDeliveryStateOpen)), checks.StatusSkipped},
+		{"shipped with tracking code", order().Delivery(delivery(shopware.OrderDeliveryStateShipped).TrackingCodes("00340434161094042557")), checks.StatusPassed},
+		{"shipped without tracking code", order().Delivery(delivery(shopware.OrderDeliveryStateShipped)), checks.StatusFailed},
+		{"shipped with empty tracking code", order().Delivery(delivery(shopware.OrderDeliveryStateShipped).TrackingCodes("")), checks.StatusFailed},
+	})
+}
+
+func TestShippedPdfDocument(t *testing.T) {
+	shipped := delivery(shopware.OrderDeliveryStateShipped)
+	runCheckTests(t, common.ShippedPdfDocument{}, []checkTest{
+		{"no deliveries", order().Document("pdf", "delivery_note.pdf"), checks.StatusSkipped},
+		{"open delivery", order().Delivery(delivery(shopware.OrderDeliveryStateOpen)), checks.StatusSkipped},
+		{"shipped with pdf", order().Delivery(shipped).Document("pdf", "delivery_note.pdf"), checks.StatusPassed},
+		{"shipped without document", order().Delivery(shipped), checks.StatusFailed},
+		{"shipped with xml", order().Delivery(shipped).Document("xml", "invoice.xml"), checks.StatusFailed},
+	})
+}
+
+func TestDoneDeliveryNotOpen(t *testing.T) {
+	done := func() *shopwaretest.OrderBuilder {
+		return order().State(shopware.OrderStateDone)
+	}
+	runCheckTests(t, common.DoneDeliveryNotOpen{}, []checkTest{
+		{"open order", order().Delivery(delivery(shopware.OrderDeliveryStateOpen)), checks.StatusSkipped},
+		{"done with shipped delivery", done().Delivery(delivery(shopware.OrderDeliveryStateShipped)), checks.StatusPassed},
+		{"done with open delivery", done().Delivery(delivery(shopware.OrderDeliveryStateOpen)), checks.StatusFailed},
+		{"done without deliveries", done(), checks.StatusFailed},
+	})
+}
+
+func TestReturnedRefundedState(t *testing.T) {
+	at := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	tx := func(state shopware.OrderTransactionState, createdAt time.Time) *shopwaretest.TransactionBuilder {
+		return shopwaretest.NewTransaction(state).CreatedAt(createdAt)
+	}
+	returned := delivery(shopware.OrderDeliveryStateReturned)
+	returnedPartially := delivery(shopware.OrderDeliveryStateReturnedPartially)
+
+	runCheckTests(t, common.ReturnedRefundedState{}, []checkTest{
+		{"no deliveries", order(), checks.StatusSkipped},
+		{"shipped delivery", order().Delivery(delivery(shopware.OrderDeliveryStateShipped)).Transaction(tx(shopware.OrderTransactionStatePaid, at)), checks.StatusSkipped},
+		{"returned and refunded", order().Delivery(returned).Transaction(tx(shopware.OrderTransactionStateRefunded, at)), checks.StatusPassed},
+		{"returned and paid", order().Delivery(returned).Transaction(tx(shopware.OrderTransactionStatePaid, at)), checks.StatusFailed},
+		{"returned without transactions", order().Delivery(returned), checks.StatusFailed},
+		{"returned and refunded before paid again", order().Delivery(returned).
+			Transaction(tx(shopware.OrderTransactionStateRefunded, at)).
+			Transaction(tx(shopware.OrderTransactionStatePaid, at.Add(time.Hour))), checks.StatusFailed},
+		{"returned and paid before refunded", order().Delivery(returned).
+			Transaction(tx(shopware.OrderTransactionStatePaid, at)).
+			Transaction(tx(shopware.OrderTransactionStateRefunded, at.Add(time.Hour))), checks.StatusPassed},
+		{"partially returned and partially refunded", order().Delivery(returnedPartially).
+			Transaction(tx(shopware.OrderTransactionStateRefundedPartially, at)), checks.StatusPassed},
+		{"partially returned and fully refunded", order().Delivery(returnedPartially).
+			Transaction(tx(shopware.OrderTransactionStateRefunded, at)), checks.StatusFailed},
+	})
+}
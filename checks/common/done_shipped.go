@@ -2,16 +2,16 @@ package common
 
 import (
 	"fmt"
-	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
+	"github.com/nikolayk812/shopware-orders-scanner/checks"
 	"github.com/nikolayk812/shopware-orders-scanner/domain"
 )
 
 type DoneDeliveryNotOpen struct{}
 
-func (_ DoneDeliveryNotOpen) Apply(order shopware.Order) (bool, error) {
+func (_ DoneDeliveryNotOpen) Apply(order domain.Order) (bool, error) {
 	// pre-condition
-	if order.StateMachineState.Name != shopware.OrderStateDone {
-		return false, nil
+	if order.State != domain.OrderStateDone {
+		return false, checks.Skip("order state [%s] is not [%s]", order.State, domain.OrderStateDone)
 	}
 
 	// check
@@ -19,10 +19,22 @@ func (_ DoneDeliveryNotOpen) Apply(order shopware.Order) (bool, error) {
 	if !ok {
 		return false, fmt.Errorf("no deliveries")
 	}
-	if d.StateMachineState.Name == shopware.OrderDeliveryStateOpen {
+	if d.State == domain.DeliveryStateOpen {
 		return false, fmt.Errorf("wrong delivery state [%s] when shipped",
-			d.StateMachineState.Name)
+			d.State)
 	}
 
 	return true, nil
 }
+
+func (_ DoneDeliveryNotOpen) Fields() []domain.Field {
+	return []domain.Field{domain.FieldState, domain.FieldDeliveryState}
+}
+
+func (_ DoneDeliveryNotOpen) Description() string {
+	return "a done order has a delivery which is not open"
+}
+
+func (_ DoneDeliveryNotOpen) Severity() checks.Severity {
+	return checks.SeverityLow
+}
@@ -2,17 +2,17 @@ package common
 
 import (
 	"fmt"
-	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
+	"github.com/nikolayk812/shopware-orders-scanner/checks"
 	"github.com/nikolayk812/shopware-orders-scanner/domain"
 )
 
 type ReturnedRefundedState struct{}
 
-func (_ ReturnedRefundedState) Apply(order shopware.Order) (bool, error) {
+func (_ ReturnedRefundedState) Apply(order domain.Order) (bool, error) {
 	// pre-conditions
 	d, ok := domain.FirstDelivery(order)
 	if !ok {
-		return false, nil
+		return false, checks.Skip("no deliveries")
 	}
 
 	// check
@@ -21,21 +21,34 @@ func (_ ReturnedRefundedState) Apply(order shopware.Order) (bool, error) {
 		return false, fmt.Errorf("no transactions")
 	}
 
-	txState := tx.StateMachineState.Name
-	switch d.StateMachineState.Name {
-	case shopware.OrderDeliveryStateReturned:
-		if txState != shopware.OrderTransactionStateRefunded {
+	txState := tx.State
+	switch d.State {
+	case domain.DeliveryStateReturned:
+		if txState != domain.TransactionStateRefunded {
 			return false, fmt.Errorf("wrong payment state [%s] expected [%s]",
-				txState, shopware.OrderTransactionStateRefunded)
+				txState, domain.TransactionStateRefunded)
 		}
-	case shopware.OrderDeliveryStateReturnedPartially:
-		if txState != shopware.OrderTransactionStateRefundedPartially {
+	case domain.DeliveryStateReturnedPartially:
+		if txState != domain.TransactionStateRefundedPartially {
 			return false, fmt.Errorf("wrong payment state [%s] expected [%s]",
-				txState, shopware.OrderTransactionStateRefundedPartially)
+				txState, domain.TransactionStateRefundedPartially)
 		}
 	default:
-		return false, nil //pre-conditions are not met
+		return false, checks.Skip("delivery state [%s] is neither [%s] nor [%s]", d.State,
+			domain.DeliveryStateReturned, domain.DeliveryStateReturnedPartially)
 	}
 
 	return true, nil
 }
+
+func (_ ReturnedRefundedState) Fields() []domain.Field {
+	return []domain.Field{domain.FieldDeliveryState, domain.FieldTransactionState, domain.FieldTransactionCreatedAt}
+}
+
+func (_ ReturnedRefundedState) Description() string {
+	return "a returned delivery has its latest payment refunded, a partially returned one partially refunded"
+}
+
+func (_ ReturnedRefundedState) Severity() checks.Severity {
+	return checks.SeverityHigh
+}
@@ -2,20 +2,20 @@ package common
 
 import (
 	"fmt"
-	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
+	"github.com/nikolayk812/shopware-orders-scanner/checks"
 	"github.com/nikolayk812/shopware-orders-scanner/domain"
 )
 
 type ShippedTrackingCode struct{}
 
-func (_ ShippedTrackingCode) Apply(order shopware.Order) (bool, error) {
+func (_ ShippedTrackingCode) Apply(order domain.Order) (bool, error) {
 	// pre-condition
 	d, ok := domain.FirstDelivery(order)
 	if !ok {
-		return false, nil
+		return false, checks.Skip("no deliveries")
 	}
-	if d.StateMachineState.Name != shopware.OrderDeliveryStateShipped {
-		return false, nil
+	if d.State != domain.DeliveryStateShipped {
+		return false, checks.Skip("delivery state [%s] is not [%s]", d.State, domain.DeliveryStateShipped)
 	}
 
 	// check
@@ -30,3 +30,15 @@ func (_ ShippedTrackingCode) Apply(order shopware.Order) (bool, error) {
 
 	return true, nil
 }
+
+func (_ ShippedTrackingCode) Fields() []domain.Field {
+	return []domain.Field{domain.FieldDeliveryState, domain.FieldDeliveryTrackingCodes}
+}
+
+func (_ ShippedTrackingCode) Description() string {
+	return "a shipped delivery has a non-empty tracking code"
+}
+
+func (_ ShippedTrackingCode) Severity() checks.Severity {
+	return checks.SeverityHigh
+}
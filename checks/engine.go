@@ -1,8 +1,10 @@
 package checks
 
 import (
-	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
+	"errors"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
 	"go.uber.org/zap"
+	"sort"
 )
 
 type Engine struct {
@@ -13,23 +15,101 @@ func NewEngine(rules map[string]Check) Engine {
 	return Engine{rules: rules}
 }
 
-func (e Engine) ProcessOrder(order shopware.Order) map[string]error {
+type Status string
+
+const (
+	StatusPassed  Status = "PASSED"
+	StatusSkipped Status = "SKIPPED"
+	StatusFailed  Status = "FAILED"
+)
+
+// Outcome of a single rule applied to an order, Err is set for failures only
+// and Reason for skipped rules whose check has explained it.
+type Outcome struct {
+	Rule        string
+	Description string
+	Status      Status
+	Err         error
+	Reason      string
+}
+
+// ProcessOrder returns errors of failed rules only.
+func (e Engine) ProcessOrder(order domain.Order) map[string]error {
 	errors := map[string]error{}
-	for ruleName, rule := range e.rules {
-		_, err := rule.Apply(order)
-		if err != nil {
-			errors[ruleName] = err
+	for _, outcome := range e.Evaluate(order) {
+		if outcome.Status == StatusFailed {
+			errors[outcome.Rule] = outcome.Err
 		}
-		processResult(err, order, ruleName)
 	}
 	return errors
 }
 
-func processResult(err error, order shopware.Order, ruleName string) {
-	if err != nil {
-		zap.S().Errorf("order [%s] has failed to pass [%s] check : %v", order.ID, ruleName, err)
-		return
+// Evaluate returns outcomes of all rules sorted by rule name.
+func (e Engine) Evaluate(order domain.Order) []Outcome {
+	outcomes := make([]Outcome, 0, len(e.rules))
+	for ruleName, rule := range e.rules {
+		ok, err := rule.Apply(order)
+		outcome := Outcome{Rule: ruleName, Description: rule.Description(), Status: StatusSkipped}
+		var skip SkipError
+		switch {
+		case errors.As(err, &skip):
+			outcome.Reason = skip.Reason
+			err = nil
+		case err != nil:
+			outcome.Status, outcome.Err = StatusFailed, err
+		case ok:
+			outcome.Status = StatusPassed
+		}
+		outcomes = append(outcomes, outcome)
+		processResult(outcome, order)
+	}
+
+	sort.Slice(outcomes, func(i, j int) bool {
+		return outcomes[i].Rule < outcomes[j].Rule
+	})
+	return outcomes
+}
+
+// Fields returns the fields read by any of the rules.
+func (e Engine) Fields() []domain.Field {
+	var fields []domain.Field
+	for _, rule := range e.rules {
+		fields = append(fields, rule.Fields()...)
+	}
+	return fields
+}
+
+// Rules returns names of all rules, sorted.
+func (e Engine) Rules() []string {
+	var names []string
+	for ruleName := range e.rules {
+		names = append(names, ruleName)
 	}
+	sort.Strings(names)
+	return names
+}
 
-	zap.S().Debugf("order [%s] has passed [%s] check", order.ID, ruleName)
+// Severities returns the severity of every rule.
+func (e Engine) Severities() map[string]Severity {
+	severities := map[string]Severity{}
+	for ruleName, rule := range e.rules {
+		severities[ruleName] = rule.Severity()
+	}
+	return severities
+}
+
+func processResult(outcome Outcome, order domain.Order) {
+	fields := []zap.Field{
+		zap.String("order_id", order.ID),
+		zap.String("order_number", order.Number),
+		zap.String("rule", outcome.Rule),
+	}
+	switch outcome.Status {
+	case StatusFailed:
+		zap.L().Error("order has failed to pass check", append(fields, zap.Error(outcome.Err))...)
+	case StatusPassed:
+		zap.L().Debug("order has passed check", fields...)
+	case StatusSkipped:
+		zap.L().Debug("order has skipped check", append(fields, zap.String("reason", outcome.Reason))...)
+	}
 }
@@ -0,0 +1,51 @@
+package shopware
+
+import "sort"
+
+// Entity names used as keys of Includes.
+const (
+	EntityOrder             = "order"
+	EntityOrderDelivery     = "order_delivery"
+	EntityOrderTransaction  = "order_transaction"
+	EntityOrderLineItem     = "order_line_item"
+	EntityDocument          = "document"
+	EntityStateMachineState = "state_machine_state"
+)
+
+// Includes restricts the fields returned per entity name as described in
+// https://docs.shopware.com/en/shopware-platform-dev-en/api/filter-search-limit#includes
+// Order fields naming an association, i.e. deliveries, also make the association to be loaded.
+type Includes map[string][]string
+
+// MergeIncludes returns the union of all fields per entity, sorted and without duplicates.
+func MergeIncludes(includes ...Includes) Includes {
+	fields := map[string]map[string]bool{}
+	for _, inc := range includes {
+		for entity, ff := range inc {
+			if fields[entity] == nil {
+				fields[entity] = map[string]bool{}
+			}
+			for _, f := range ff {
+				fields[entity][f] = true
+			}
+		}
+	}
+
+	result := Includes{}
+	for entity, ff := range fields {
+		for f := range ff {
+			result[entity] = append(result[entity], f)
+		}
+		sort.Strings(result[entity])
+	}
+	return result
+}
+
+func (i Includes) has(entity, field string) bool {
+	for _, f := range i[entity] {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
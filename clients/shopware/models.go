@@ -23,8 +23,15 @@ type filter struct {
 }
 
 type filterParameters struct {
-	GTE string `json:"gte"`
-	LTE string `json:"lte"`
+	GT  string `json:"gt,omitempty"`
+	GTE string `json:"gte,omitempty"`
+	LTE string `json:"lte,omitempty"`
+}
+
+// sorting as described in https://docs.shopware.com/en/shopware-platform-dev-en/api/filter-search-limit#sort
+type sorting struct {
+	Field string `json:"field"`
+	Order string `json:"order"`
 }
 
 // FilterType as described in https://docs.shopware.com/en/shopware-platform-dev-en/api/filter-search-limit#filter-1
@@ -53,6 +60,7 @@ type Order struct {
 }
 
 type OrderDelivery struct {
+	ID                string   `json:"id"`
 	OrderID           string   `json:"orderId"`
 	TrackingCodes     []string `json:"trackingCodes"`
 	UpdatedAt         string   `json:"updatedAt"`
@@ -62,11 +70,13 @@ type OrderDelivery struct {
 }
 
 type OrderTransaction struct {
+	ID                string `json:"id"`
 	OrderID           string `json:"orderId"`
 	StateMachineState struct {
 		Name OrderTransactionState `json:"name"`
 	} `json:"stateMachineState"`
 	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt string    `json:"updatedAt"`
 }
 
 type OrderDocument struct {
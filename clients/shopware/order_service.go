@@ -8,47 +8,58 @@ import (
 
 const timeFormat = "2006-01-02 15:04:05"
 
+// OrderService searches orders and their deliveries and transactions.
+// Time range searches are sorted by id and paginated keyset-style:
+// afterID is the id of the last entity of the previous page, empty for the first page.
 type OrderService interface {
-	SearchByTimeRange(ctx context.Context, field string, gte, lte time.Time, page int) ([]Order, error)
+	SearchByTimeRange(ctx context.Context, field string, gte, lte time.Time, afterID string) ([]Order, error)
 	SearchByIDs(ctx context.Context, IDs []string) ([]Order, error)
-	SearchDeliveriesByTimeRange(ctx context.Context, field string, gte, lte time.Time, page int) ([]OrderDelivery, error)
-	SearchTransactionsByTimeRange(ctx context.Context, field string, gte, lte time.Time, page int) ([]OrderTransaction, error)
+	SearchByNumbers(ctx context.Context, numbers []string) ([]Order, error)
+	SearchByStates(ctx context.Context, states []OrderState, afterID string) ([]Order, error)
+	SearchDeliveriesByTimeRange(ctx context.Context, field string, gte, lte time.Time, afterID string) ([]OrderDelivery, error)
+	SearchTransactionsByTimeRange(ctx context.Context, field string, gte, lte time.Time, afterID string) ([]OrderTransaction, error)
 }
 
 type orderService struct {
 	client        *resty.Client
 	tokenProvider TokenProvider
+	includes      Includes
 }
 
-func NewOrderService(client *resty.Client, provider TokenProvider) OrderService {
+// NewOrderService creates a service which returns only the order fields and associations listed in includes.
+// Empty includes return all fields of all associations.
+func NewOrderService(client *resty.Client, provider TokenProvider, includes Includes) OrderService {
+	if len(includes[EntityOrder]) > 0 {
+		// keyset pagination relies on ids
+		includes = MergeIncludes(includes, Includes{EntityOrder: {"id"}})
+	}
+
 	return &orderService{
 		client:        client,
 		tokenProvider: provider,
+		includes:      includes,
 	}
 }
 
-func (s *orderService) SearchByTimeRange(ctx context.Context, field string, gte, lte time.Time, page int) ([]Order, error) {
+func (s *orderService) SearchByTimeRange(ctx context.Context, field string, gte, lte time.Time, afterID string) ([]Order, error) {
 	path := "/api/v3/search/order"
 
 	type request struct {
 		Page         int          `json:"page"`
 		Limit        int          `json:"limit"`
 		Filters      []filter     `json:"filter"`
+		Sort         []sorting    `json:"sort"`
 		Associations associations `json:"associations"`
+		Includes     Includes     `json:"includes,omitempty"`
 	}
 
 	body := request{
-		Page:  page,
-		Limit: MaxSearchLimit,
-		Filters: []filter{{
-			Type:  filterTypeRange,
-			Field: field,
-			Parameters: &filterParameters{
-				GTE: gte.Format(timeFormat),
-				LTE: lte.Format(timeFormat),
-			},
-		}},
-		Associations: orderAssociations(),
+		Page:         1,
+		Limit:        MaxSearchLimit,
+		Filters:      keysetFilters(field, gte, lte, afterID),
+		Sort:         idSorting(),
+		Associations: orderAssociations(s.includes),
+		Includes:     s.includes,
 	}
 
 	var result struct {
@@ -78,6 +89,7 @@ func (s *orderService) SearchByIDs(ctx context.Context, IDs []string) ([]Order,
 		Limit        int          `json:"limit"`
 		Filters      []filter     `json:"filter"`
 		Associations associations `json:"associations"`
+		Includes     Includes     `json:"includes,omitempty"`
 	}
 
 	body := request{
@@ -88,7 +100,8 @@ func (s *orderService) SearchByIDs(ctx context.Context, IDs []string) ([]Order,
 			Field: "id",
 			Value: IDs,
 		}},
-		Associations: orderAssociations(),
+		Associations: orderAssociations(s.includes),
+		Includes:     s.includes,
 	}
 
 	var result struct {
@@ -110,26 +123,118 @@ func (s *orderService) SearchByIDs(ctx context.Context, IDs []string) ([]Order,
 	return result.Data, nil
 }
 
-func (s *orderService) SearchDeliveriesByTimeRange(ctx context.Context, field string, gte, lte time.Time, page int) ([]OrderDelivery, error) {
-	path := "/api/v3/search/order-delivery"
+func (s *orderService) SearchByNumbers(ctx context.Context, numbers []string) ([]Order, error) {
+	path := "/api/v3/search/order"
 
 	type request struct {
-		Page    int      `json:"page"`
-		Limit   int      `json:"limit"`
-		Filters []filter `json:"filter"`
+		Page         int          `json:"page"`
+		Limit        int          `json:"limit"`
+		Filters      []filter     `json:"filter"`
+		Associations associations `json:"associations"`
+		Includes     Includes     `json:"includes,omitempty"`
 	}
 
 	body := request{
-		Page:  page,
+		Page:  1,
 		Limit: MaxSearchLimit,
 		Filters: []filter{{
-			Type:  filterTypeRange,
-			Field: field,
-			Parameters: &filterParameters{
-				GTE: gte.Format(timeFormat),
-				LTE: lte.Format(timeFormat),
-			},
+			Type:  filterTypeEqualsAny,
+			Field: "orderNumber",
+			Value: numbers,
 		}},
+		Associations: orderAssociations(s.includes),
+		Includes:     s.includes,
+	}
+
+	var result struct {
+		Total int     `json:"total"`
+		Data  []Order `json:"data"`
+	}
+
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetHeaders(s.headers()).
+		SetBody(body).
+		SetResult(&result).
+		Post(path)
+
+	if err := checkHttpResp(resp, err); err != nil {
+		return nil, err
+	}
+
+	return result.Data, nil
+}
+
+func (s *orderService) SearchByStates(ctx context.Context, states []OrderState, afterID string) ([]Order, error) {
+	path := "/api/v3/search/order"
+
+	type request struct {
+		Page         int          `json:"page"`
+		Limit        int          `json:"limit"`
+		Filters      []filter     `json:"filter"`
+		Sort         []sorting    `json:"sort"`
+		Associations associations `json:"associations"`
+		Includes     Includes     `json:"includes,omitempty"`
+	}
+
+	filters := []filter{{
+		Type:  filterTypeEqualsAny,
+		Field: "stateMachineState.name",
+		Value: states,
+	}}
+	if afterID != "" {
+		filters = append(filters, filter{
+			Type:       filterTypeRange,
+			Field:      "id",
+			Parameters: &filterParameters{GT: afterID},
+		})
+	}
+
+	body := request{
+		Page:         1,
+		Limit:        MaxSearchLimit,
+		Filters:      filters,
+		Sort:         idSorting(),
+		Associations: orderAssociations(s.includes),
+		Includes:     s.includes,
+	}
+
+	var result struct {
+		Total int     `json:"total"`
+		Data  []Order `json:"data"`
+	}
+
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetHeaders(s.headers()).
+		SetBody(body).
+		SetResult(&result).
+		Post(path)
+
+	if err := checkHttpResp(resp, err); err != nil {
+		return nil, err
+	}
+
+	return result.Data, nil
+}
+
+func (s *orderService) SearchDeliveriesByTimeRange(ctx context.Context, field string, gte, lte time.Time, afterID string) ([]OrderDelivery, error) {
+	path := "/api/v3/search/order-delivery"
+
+	type request struct {
+		Page     int       `json:"page"`
+		Limit    int       `json:"limit"`
+		Filters  []filter  `json:"filter"`
+		Sort     []sorting `json:"sort"`
+		Includes Includes  `json:"includes"`
+	}
+
+	body := request{
+		Page:     1,
+		Limit:    MaxSearchLimit,
+		Filters:  keysetFilters(field, gte, lte, afterID),
+		Sort:     idSorting(),
+		Includes: Includes{EntityOrderDelivery: {"id", "orderId"}},
 	}
 
 	var result struct {
@@ -151,26 +256,23 @@ func (s *orderService) SearchDeliveriesByTimeRange(ctx context.Context, field st
 	return result.Data, nil
 }
 
-func (s *orderService) SearchTransactionsByTimeRange(ctx context.Context, field string, gte, lte time.Time, page int) ([]OrderTransaction, error) {
+func (s *orderService) SearchTransactionsByTimeRange(ctx context.Context, field string, gte, lte time.Time, afterID string) ([]OrderTransaction, error) {
 	path := "/api/v3/search/order-transaction"
 
 	type request struct {
-		Page    int      `json:"page"`
-		Limit   int      `json:"limit"`
-		Filters []filter `json:"filter"`
+		Page     int       `json:"page"`
+		Limit    int       `json:"limit"`
+		Filters  []filter  `json:"filter"`
+		Sort     []sorting `json:"sort"`
+		Includes Includes  `json:"includes"`
 	}
 
 	body := request{
-		Page:  page,
-		Limit: MaxSearchLimit,
-		Filters: []filter{{
-			Type:  filterTypeRange,
-			Field: field,
-			Parameters: &filterParameters{
-				GTE: gte.Format(timeFormat),
-				LTE: lte.Format(timeFormat),
-			},
-		}},
+		Page:     1,
+		Limit:    MaxSearchLimit,
+		Filters:  keysetFilters(field, gte, lte, afterID),
+		Sort:     idSorting(),
+		Includes: Includes{EntityOrderTransaction: {"id", "orderId"}},
 	}
 
 	var result struct {
@@ -192,22 +294,47 @@ func (s *orderService) SearchTransactionsByTimeRange(ctx context.Context, field
 	return result.Data, nil
 }
 
+// keysetFilters restricts field to the [gte, lte] range in UTC, as Shopware stores it, and, unless it is the first page,
+// to ids greater than afterID.
+func keysetFilters(field string, gte, lte time.Time, afterID string) []filter {
+	filters := []filter{{
+		Type:  filterTypeRange,
+		Field: field,
+		Parameters: &filterParameters{
+			GTE: gte.UTC().Format(timeFormat),
+			LTE: lte.UTC().Format(timeFormat),
+		},
+	}}
+
+	if afterID != "" {
+		filters = append(filters, filter{
+			Type:       filterTypeRange,
+			Field:      "id",
+			Parameters: &filterParameters{GT: afterID},
+		})
+	}
+
+	return filters
+}
+
+// idSorting is deterministic regardless of concurrent updates of the searched entities.
+func idSorting() []sorting {
+	return []sorting{{Field: "id", Order: "ASC"}}
+}
+
 func (s *orderService) headers() map[string]string {
 	return tokenHeaders(s.tokenProvider.GetToken())
 }
 
-type associations struct {
-	Deliveries   []interface{} `json:"deliveries"`
-	Transactions []interface{} `json:"transactions"`
-	Documents    []interface{} `json:"documents"`
-	LineItems    []interface{} `json:"lineItems"`
-}
+type associations map[string][]interface{}
 
-func orderAssociations() associations {
-	return associations{
-		Deliveries:   []interface{}{},
-		Transactions: []interface{}{},
-		Documents:    []interface{}{},
-		LineItems:    []interface{}{},
+// orderAssociations loads the associations listed among the order includes, all of them if there are no includes.
+func orderAssociations(includes Includes) associations {
+	result := associations{}
+	for _, name := range []string{"deliveries", "transactions", "documents", "lineItems"} {
+		if len(includes[EntityOrder]) == 0 || includes.has(EntityOrder, name) {
+			result[name] = []interface{}{}
+		}
 	}
+	return result
 }
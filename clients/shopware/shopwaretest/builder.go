@@ -0,0 +1,155 @@
+package shopwaretest
+
+import (
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
+	"time"
+)
+
+// ID returns a Shopware-like hex id, ids of increasing n sort in the same order.
+func ID(n int) string {
+	return fmt.Sprintf("%032x", n)
+}
+
+// OrderBuilder builds shopware.Order fixtures, by default an open order created and updated at the Unix epoch.
+type OrderBuilder struct {
+	order shopware.Order
+}
+
+func NewOrder(id string) *OrderBuilder {
+	b := &OrderBuilder{}
+	b.order.ID = id
+	b.order.Number = id
+	b.order.StateMachineState.Name = shopware.OrderStateOpen
+	b.order.CreatedAt = formatTime(time.Unix(0, 0))
+	b.order.UpdatedAt = b.order.CreatedAt
+	return b
+}
+
+func (b *OrderBuilder) Number(number string) *OrderBuilder {
+	b.order.Number = number
+	return b
+}
+
+func (b *OrderBuilder) SalesChannel(id string) *OrderBuilder {
+	b.order.SalesChannelID = id
+	return b
+}
+
+func (b *OrderBuilder) State(state shopware.OrderState) *OrderBuilder {
+	b.order.StateMachineState.Name = state
+	return b
+}
+
+func (b *OrderBuilder) CreatedAt(t time.Time) *OrderBuilder {
+	b.order.CreatedAt = formatTime(t)
+	return b
+}
+
+func (b *OrderBuilder) UpdatedAt(t time.Time) *OrderBuilder {
+	b.order.UpdatedAt = formatTime(t)
+	return b
+}
+
+// Delivery appends a delivery, its id defaults to one derived from the order id.
+func (b *OrderBuilder) Delivery(d *DeliveryBuilder) *OrderBuilder {
+	delivery := d.delivery
+	delivery.OrderID = b.order.ID
+	if delivery.ID == "" {
+		delivery.ID = fmt.Sprintf("%s-d%d", b.order.ID, len(b.order.Deliveries))
+	}
+	b.order.Deliveries = append(b.order.Deliveries, delivery)
+	return b
+}
+
+// Transaction appends a transaction, its id defaults to one derived from the order id.
+func (b *OrderBuilder) Transaction(t *TransactionBuilder) *OrderBuilder {
+	tx := t.tx
+	tx.OrderID = b.order.ID
+	if tx.ID == "" {
+		tx.ID = fmt.Sprintf("%s-t%d", b.order.ID, len(b.order.Transactions))
+	}
+	b.order.Transactions = append(b.order.Transactions, tx)
+	return b
+}
+
+func (b *OrderBuilder) Document(fileType, fileName string) *OrderBuilder {
+	var doc shopware.OrderDocument
+	doc.FileType = fileType
+	doc.Config.Custom.FileName = fileName
+	b.order.Documents = append(b.order.Documents, doc)
+	return b
+}
+
+func (b *OrderBuilder) LineItem(productID, productNumber string) *OrderBuilder {
+	var item shopware.LineItem
+	item.ProductID = productID
+	item.Payload.ProductNumber = productNumber
+	b.order.LineItems = append(b.order.LineItems, item)
+	return b
+}
+
+// Build returns a copy, so the builder can be reused for similar orders.
+func (b *OrderBuilder) Build() shopware.Order {
+	order := b.order
+	order.Deliveries = append([]shopware.OrderDelivery(nil), b.order.Deliveries...)
+	order.Transactions = append([]shopware.OrderTransaction(nil), b.order.Transactions...)
+	order.Documents = append([]shopware.OrderDocument(nil), b.order.Documents...)
+	order.LineItems = append([]shopware.LineItem(nil), b.order.LineItems...)
+	return order
+}
+
+type DeliveryBuilder struct {
+	delivery shopware.OrderDelivery
+}
+
+// NewDelivery starts a delivery in the given state, updated at the Unix epoch.
+func NewDelivery(state shopware.OrderDeliveryState) *DeliveryBuilder {
+	b := &DeliveryBuilder{}
+	b.delivery.StateMachineState.Name = state
+	b.delivery.UpdatedAt = formatTime(time.Unix(0, 0))
+	return b
+}
+
+func (b *DeliveryBuilder) ID(id string) *DeliveryBuilder {
+	b.delivery.ID = id
+	return b
+}
+
+func (b *DeliveryBuilder) TrackingCodes(codes ...string) *DeliveryBuilder {
+	b.delivery.TrackingCodes = codes
+	return b
+}
+
+func (b *DeliveryBuilder) UpdatedAt(t time.Time) *DeliveryBuilder {
+	b.delivery.UpdatedAt = formatTime(t)
+	return b
+}
+
+type TransactionBuilder struct {
+	tx shopware.OrderTransaction
+}
+
+// NewTransaction starts a transaction in the given state, created and updated at the Unix epoch.
+func NewTransaction(state shopware.OrderTransactionState) *TransactionBuilder {
+	b := &TransactionBuilder{}
+	b.tx.StateMachineState.Name = state
+	b.tx.CreatedAt = time.Unix(0, 0).UTC()
+	b.tx.UpdatedAt = formatTime(b.tx.CreatedAt)
+	return b
+}
+
+func (b *TransactionBuilder) ID(id string) *TransactionBuilder {
+	b.tx.ID = id
+	return b
+}
+
+func (b *TransactionBuilder) CreatedAt(t time.Time) *TransactionBuilder {
+	b.tx.CreatedAt = t
+	return b
+}
+
+func (b *TransactionBuilder) UpdatedAt(t time.Time) *TransactionBuilder {
+	b.tx.UpdatedAt = formatTime(t)
+	return b
+}
@@ -0,0 +1,270 @@
+package shopwaretest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CassetteModeEnv selects whether cassettes record real Shopware responses or replay recorded ones.
+const CassetteModeEnv = "SHOPWARE_CASSETTE_MODE"
+
+type CassetteMode string
+
+const (
+	CassetteModeReplay CassetteMode = "replay"
+	CassetteModeRecord CassetteMode = "record"
+)
+
+// Redacted replaces scrubbed values in cassettes.
+const Redacted = "REDACTED"
+
+// ScrubbedFields are JSON keys whose values are replaced by Redacted before recording at any nesting level:
+// credentials, tokens and customer PII.
+var ScrubbedFields = map[string]bool{
+	"client_id":     true,
+	"client_secret": true,
+	"access_token":  true,
+	"refresh_token": true,
+
+	"email":           true,
+	"firstName":       true,
+	"lastName":        true,
+	"title":           true,
+	"company":         true,
+	"street":          true,
+	"zipcode":         true,
+	"city":            true,
+	"phoneNumber":     true,
+	"vatId":           true,
+	"vatIds":          true,
+	"customerNumber":  true,
+	"remoteAddress":   true,
+	"customerComment": true,
+}
+
+// Interaction is a recorded request and its response, without headers.
+type Interaction struct {
+	Request struct {
+		Method string          `json:"method"`
+		URL    string          `json:"url"`
+		Body   json.RawMessage `json:"body,omitempty"`
+	} `json:"request"`
+	Response struct {
+		Status      int             `json:"status"`
+		ContentType string          `json:"contentType,omitempty"`
+		Body        json.RawMessage `json:"body,omitempty"`
+	} `json:"response"`
+}
+
+// Cassette is an http.RoundTripper recording interactions with a real Shopware into a file
+// or replaying them from it, depending on CassetteModeEnv. Requests are matched by method,
+// path with query and the scrubbed body, repeated requests like token refreshes replay the last match.
+type Cassette struct {
+	path string
+	mode CassetteMode
+	next http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction // guarded by mu
+	used         []bool        // guarded by mu
+}
+
+// NewCassette loads the cassette file in replay mode. In record mode requests are sent via http.DefaultTransport,
+// call Save to write the recorded interactions.
+func NewCassette(path string) (*Cassette, error) {
+	mode := CassetteMode(os.Getenv(CassetteModeEnv))
+	if mode == "" {
+		mode = CassetteModeReplay
+	}
+
+	c := &Cassette{
+		path: path,
+		mode: mode,
+		next: http.DefaultTransport,
+	}
+
+	switch mode {
+	case CassetteModeRecord:
+		return c, nil
+	case CassetteModeReplay:
+		bytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("ioutil.ReadFile [%s] : %w", path, err)
+		}
+		if err := json.Unmarshal(bytes, &c.interactions); err != nil {
+			return nil, fmt.Errorf("json.Unmarshal [%s] : %w", path, err)
+		}
+		for idx := range c.interactions {
+			// canonical form for matching, the file is indented
+			c.interactions[idx].Request.Body = scrub(c.interactions[idx].Request.Body)
+		}
+		c.used = make([]bool, len(c.interactions))
+		return c, nil
+	default:
+		return nil, fmt.Errorf("unknown %s [%s]", CassetteModeEnv, mode)
+	}
+}
+
+func (c *Cassette) Mode() CassetteMode {
+	return c.mode
+}
+
+// Client returns a resty client sending requests through the cassette,
+// baseURL is only contacted in record mode.
+func (c *Cassette) Client(baseURL string) *resty.Client {
+	return resty.New().SetHostURL(baseURL).SetTransport(c)
+}
+
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("read request body : %w", err)
+		}
+		reqBody = b
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+
+	var i Interaction
+	i.Request.Method = req.Method
+	i.Request.URL = req.URL.RequestURI()
+	i.Request.Body = scrub(reqBody)
+
+	if c.mode == CassetteModeReplay {
+		return c.replay(req, i)
+	}
+	return c.record(req, i)
+}
+
+func (c *Cassette) record(req *http.Request, i Interaction) (*http.Response, error) {
+	resp, err := c.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response body : %w", err)
+	}
+
+	i.Response.Status = resp.StatusCode
+	i.Response.ContentType = resp.Header.Get("Content-Type")
+	i.Response.Body = scrub(respBody)
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, i)
+	c.mu.Unlock()
+
+	// the caller gets the real response, only the cassette is scrubbed
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
+
+func (c *Cassette) replay(req *http.Request, i Interaction) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	match := -1
+	for idx, recorded := range c.interactions {
+		if recorded.Request.Method != i.Request.Method || recorded.Request.URL != i.Request.URL ||
+			!bytes.Equal(recorded.Request.Body, i.Request.Body) {
+			continue
+		}
+		match = idx
+		if !c.used[idx] {
+			break
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("no recorded interaction for %s %s %s", i.Request.Method, i.Request.URL, i.Request.Body)
+	}
+	c.used[match] = true
+
+	recorded := c.interactions[match]
+	header := http.Header{}
+	if recorded.Response.ContentType != "" {
+		header.Set("Content-Type", recorded.Response.ContentType)
+	}
+	return &http.Response{
+		Status:        http.StatusText(recorded.Response.Status),
+		StatusCode:    recorded.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(recorded.Response.Body)),
+		ContentLength: int64(len(recorded.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Save writes recorded interactions, it does nothing in replay mode.
+func (c *Cassette) Save() error {
+	if c.mode != CassetteModeRecord {
+		return nil
+	}
+
+	c.mu.Lock()
+	bytes, err := json.MarshalIndent(c.interactions, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent : %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("os.MkdirAll : %w", err)
+	}
+	if err := ioutil.WriteFile(c.path, bytes, 0644); err != nil {
+		return fmt.Errorf("ioutil.WriteFile [%s] : %w", c.path, err)
+	}
+	return nil
+}
+
+// scrub redacts ScrubbedFields of a JSON body and re-encodes it with sorted keys, so equal bodies compare equal.
+// Bodies which are not JSON are dropped rather than risking to leak secrets.
+func scrub(body []byte) json.RawMessage {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil
+	}
+
+	scrubbed, err := json.Marshal(scrubValue(v))
+	if err != nil {
+		return nil
+	}
+	return scrubbed
+}
+
+func scrubValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, item := range t {
+			if ScrubbedFields[k] && item != nil {
+				t[k] = Redacted
+				continue
+			}
+			t[k] = scrubValue(item)
+		}
+		return t
+	case []interface{}:
+		for idx, item := range t {
+			t[idx] = scrubValue(item)
+		}
+		return t
+	default:
+		return v
+	}
+}
@@ -0,0 +1,245 @@
+package shopwaretest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// criteria is the subset of Shopware search criteria used by the shopware clients, see
+// https://docs.shopware.com/en/shopware-platform-dev-en/api/filter-search-limit
+type criteria struct {
+	Page         int                    `json:"page"`
+	Limit        int                    `json:"limit"`
+	Filters      []filter               `json:"filter"`
+	Sort         []sorting              `json:"sort"`
+	Associations map[string]interface{} `json:"associations"`
+	Includes     map[string][]string    `json:"includes"`
+}
+
+type filter struct {
+	Type       string            `json:"type"`
+	Field      string            `json:"field"`
+	Value      interface{}       `json:"value"`
+	Parameters map[string]string `json:"parameters"`
+}
+
+type sorting struct {
+	Field string `json:"field"`
+	Order string `json:"order"`
+}
+
+func (c criteria) match(e Entity) (bool, error) {
+	for _, f := range c.Filters {
+		ok, err := f.match(lookup(e, f.Field))
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (f filter) match(v interface{}) (bool, error) {
+	switch f.Type {
+	case "equals":
+		return compare(v, f.Value) == 0, nil
+	case "equalsAny":
+		values, ok := f.Value.([]interface{})
+		if !ok {
+			values = []interface{}{}
+			for _, s := range strings.Split(fmt.Sprint(f.Value), "|") {
+				values = append(values, s)
+			}
+		}
+		for _, value := range values {
+			if compare(v, value) == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "range":
+		if v == nil {
+			return false, nil
+		}
+		for op, param := range f.Parameters {
+			c := compare(v, param)
+			switch op {
+			case "gt":
+				if c <= 0 {
+					return false, nil
+				}
+			case "gte":
+				if c < 0 {
+					return false, nil
+				}
+			case "lt":
+				if c >= 0 {
+					return false, nil
+				}
+			case "lte":
+				if c > 0 {
+					return false, nil
+				}
+			default:
+				return false, fmt.Errorf("unsupported range parameter [%s]", op)
+			}
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported filter type [%s]", f.Type)
+	}
+}
+
+func (c criteria) sort(entities []Entity) {
+	sort.SliceStable(entities, func(i, j int) bool {
+		for _, s := range c.Sort {
+			r := compare(lookup(entities[i], s.Field), lookup(entities[j], s.Field))
+			if r == 0 {
+				continue
+			}
+			if strings.EqualFold(s.Order, "DESC") {
+				return r > 0
+			}
+			return r < 0
+		}
+		return false
+	})
+}
+
+// paginate returns the requested page, limit defaults to 500 as in Shopware.
+func (c criteria) paginate(entities []Entity) []Entity {
+	limit := c.Limit
+	if limit <= 0 {
+		limit = 500
+	}
+	page := c.Page
+	if page <= 0 {
+		page = 1
+	}
+
+	from := (page - 1) * limit
+	if from >= len(entities) {
+		return []Entity{}
+	}
+	to := from + limit
+	if to > len(entities) {
+		to = len(entities)
+	}
+	return entities[from:to]
+}
+
+// lookup resolves dotted field paths like stateMachineState.name, an entity prefix like order.id is ignored.
+func lookup(e Entity, field string) interface{} {
+	parts := strings.Split(field, ".")
+	if len(parts) > 1 && isEntityName(parts[0]) {
+		parts = parts[1:]
+	}
+
+	var v interface{} = map[string]interface{}(e)
+	for _, part := range parts {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	return v
+}
+
+func isEntityName(name string) bool {
+	if name == "order" || name == "product" {
+		return true
+	}
+	for _, assoc := range orderAssociations {
+		if assoc.entity == name {
+			return true
+		}
+	}
+	return false
+}
+
+// compare treats values parsable as time as such, so range filters work across date formats.
+func compare(a, b interface{}) int {
+	if ta, ok := parseTime(a); ok {
+		if tb, ok := parseTime(b); ok {
+			switch {
+			case ta.Before(tb):
+				return -1
+			case ta.After(tb):
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+
+	if fa, ok := a.(float64); ok {
+		var fb float64
+		if _, err := fmt.Sscan(fmt.Sprint(b), &fb); err == nil {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// association describes an order field holding nested entities.
+type association struct {
+	field  string
+	entity string
+}
+
+var orderAssociations = []association{
+	{"deliveries", "order_delivery"},
+	{"transactions", "order_transaction"},
+	{"documents", "document"},
+	{"lineItems", "order_line_item"},
+}
+
+// project keeps only requested associations and included fields, it copies entities rather than modifying them.
+func (c criteria) project(e Entity, entity string) Entity {
+	result := Entity{}
+	for k, v := range e {
+		result[k] = v
+	}
+
+	if entity == "order" {
+		for _, assoc := range orderAssociations {
+			if _, ok := c.Associations[assoc.field]; !ok {
+				delete(result, assoc.field)
+				continue
+			}
+			var items []interface{}
+			for _, item := range nested(e, assoc.field) {
+				items = append(items, map[string]interface{}(c.project(item, assoc.entity)))
+			}
+			result[assoc.field] = items
+		}
+	}
+
+	if state, ok := e["stateMachineState"].(map[string]interface{}); ok {
+		result["stateMachineState"] = map[string]interface{}(c.project(state, "state_machine_state"))
+	}
+
+	fields, ok := c.Includes[entity]
+	if !ok {
+		return result
+	}
+	included := Entity{}
+	for _, f := range fields {
+		if v, ok := result[f]; ok {
+			included[f] = v
+		}
+	}
+	return included
+}
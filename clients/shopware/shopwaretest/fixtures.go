@@ -0,0 +1,110 @@
+package shopwaretest
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
+	"io/ioutil"
+	"time"
+)
+
+// Entity is a Shopware entity as returned by the API, i.e. an order with nested deliveries and transactions.
+type Entity map[string]interface{}
+
+// Fixtures seed the fake server. Deliveries and transactions are searched within the orders they are nested in.
+type Fixtures struct {
+	Orders   []Entity `json:"orders"`
+	Products []Entity `json:"products"`
+}
+
+// LoadFixtures reads fixtures from a JSON file.
+func LoadFixtures(path string) (Fixtures, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Fixtures{}, fmt.Errorf("ioutil.ReadFile [%s] : %w", path, err)
+	}
+
+	var f Fixtures
+	if err := json.Unmarshal(bytes, &f); err != nil {
+		return Fixtures{}, fmt.Errorf("json.Unmarshal [%s] : %w", path, err)
+	}
+	return f, nil
+}
+
+// FixturesFromOrders seeds the fake server with orders built by OrderBuilder.
+func FixturesFromOrders(orders ...shopware.Order) (Fixtures, error) {
+	bytes, err := json.Marshal(orders)
+	if err != nil {
+		return Fixtures{}, fmt.Errorf("json.Marshal : %w", err)
+	}
+
+	var f Fixtures
+	if err := json.Unmarshal(bytes, &f.Orders); err != nil {
+		return Fixtures{}, fmt.Errorf("json.Unmarshal : %w", err)
+	}
+	return f, nil
+}
+
+// Rebase shifts createdAt and updatedAt of all orders and their associations by the same duration,
+// so the latest of them becomes latest. Handy to make static fixtures fall into the scanned time window.
+func (f Fixtures) Rebase(latest time.Time) {
+	var max time.Time
+	walkTimes(f.Orders, func(t time.Time) time.Time {
+		if t.After(max) {
+			max = t
+		}
+		return t
+	})
+	if max.IsZero() {
+		return
+	}
+
+	shift := latest.Sub(max)
+	walkTimes(f.Orders, func(t time.Time) time.Time {
+		return t.Add(shift)
+	})
+}
+
+func walkTimes(entities []Entity, f func(time.Time) time.Time) {
+	for _, e := range entities {
+		for _, field := range []string{"createdAt", "updatedAt"} {
+			if t, ok := parseTime(e[field]); ok {
+				e[field] = formatTime(f(t))
+			}
+		}
+		for _, assoc := range orderAssociations {
+			walkTimes(nested(e, assoc.field), f)
+		}
+	}
+}
+
+func nested(e Entity, field string) []Entity {
+	list, _ := e[field].([]interface{})
+	var result []Entity
+	for _, item := range list {
+		if m, ok := item.(map[string]interface{}); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// layouts of Shopware API responses and of range filter parameters
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05"}
+
+func parseTime(v interface{}) (time.Time, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000-07:00")
+}
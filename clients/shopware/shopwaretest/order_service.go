@@ -0,0 +1,170 @@
+package shopwaretest
+
+import (
+	"context"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OrderService is an in-memory shopware.OrderService. Like the real one it sorts by id,
+// returns pages of at most shopware.MaxSearchLimit rows and filters createdAt and updatedAt time ranges.
+type OrderService struct {
+	mu     sync.RWMutex
+	orders map[string]shopware.Order // guarded by mu
+}
+
+func NewOrderService(orders ...shopware.Order) *OrderService {
+	s := &OrderService{orders: map[string]shopware.Order{}}
+	s.Put(orders...)
+	return s
+}
+
+// Put adds or replaces orders, also while a scan is in progress.
+func (s *OrderService) Put(orders ...shopware.Order) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, o := range orders {
+		s.orders[o.ID] = o
+	}
+}
+
+func (s *OrderService) SearchByTimeRange(ctx context.Context, field string, gte, lte time.Time, afterID string) ([]shopware.Order, error) {
+	var result []shopware.Order
+	for _, o := range s.sorted() {
+		var value string
+		switch field {
+		case "createdAt":
+			value = o.CreatedAt
+		case "updatedAt":
+			value = o.UpdatedAt
+		default:
+			return nil, fmt.Errorf("unsupported field [%s]", field)
+		}
+
+		if o.ID > afterID && inRange(value, gte, lte) {
+			result = append(result, o)
+		}
+		if len(result) == shopware.MaxSearchLimit {
+			break
+		}
+	}
+	return result, ctx.Err()
+}
+
+func (s *OrderService) SearchByIDs(ctx context.Context, IDs []string) ([]shopware.Order, error) {
+	wanted := map[string]bool{}
+	for _, id := range IDs {
+		wanted[id] = true
+	}
+
+	var result []shopware.Order
+	for _, o := range s.sorted() {
+		if wanted[o.ID] {
+			result = append(result, o)
+		}
+	}
+	return result, ctx.Err()
+}
+
+func (s *OrderService) SearchByNumbers(ctx context.Context, numbers []string) ([]shopware.Order, error) {
+	wanted := map[string]bool{}
+	for _, n := range numbers {
+		wanted[n] = true
+	}
+
+	var result []shopware.Order
+	for _, o := range s.sorted() {
+		if wanted[o.Number] {
+			result = append(result, o)
+		}
+	}
+	return result, ctx.Err()
+}
+
+func (s *OrderService) SearchByStates(ctx context.Context, states []shopware.OrderState, afterID string) ([]shopware.Order, error) {
+	wanted := map[shopware.OrderState]bool{}
+	for _, state := range states {
+		wanted[state] = true
+	}
+
+	var result []shopware.Order
+	for _, o := range s.sorted() {
+		if o.ID > afterID && wanted[o.StateMachineState.Name] {
+			result = append(result, o)
+		}
+		if len(result) == shopware.MaxSearchLimit {
+			break
+		}
+	}
+	return result, ctx.Err()
+}
+
+func (s *OrderService) SearchDeliveriesByTimeRange(ctx context.Context, field string, gte, lte time.Time, afterID string) ([]shopware.OrderDelivery, error) {
+	if field != "updatedAt" {
+		return nil, fmt.Errorf("unsupported field [%s]", field)
+	}
+
+	var result []shopware.OrderDelivery
+	for _, o := range s.sorted() {
+		for _, d := range o.Deliveries {
+			if d.ID > afterID && inRange(d.UpdatedAt, gte, lte) {
+				result = append(result, d)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	if len(result) > shopware.MaxSearchLimit {
+		result = result[:shopware.MaxSearchLimit]
+	}
+	return result, ctx.Err()
+}
+
+func (s *OrderService) SearchTransactionsByTimeRange(ctx context.Context, field string, gte, lte time.Time, afterID string) ([]shopware.OrderTransaction, error) {
+	if field != "updatedAt" {
+		return nil, fmt.Errorf("unsupported field [%s]", field)
+	}
+
+	var result []shopware.OrderTransaction
+	for _, o := range s.sorted() {
+		for _, tx := range o.Transactions {
+			if tx.ID > afterID && inRange(tx.UpdatedAt, gte, lte) {
+				result = append(result, tx)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	if len(result) > shopware.MaxSearchLimit {
+		result = result[:shopware.MaxSearchLimit]
+	}
+	return result, ctx.Err()
+}
+
+func (s *OrderService) sorted() []shopware.Order {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]shopware.Order, 0, len(s.orders))
+	for _, o := range s.orders {
+		result = append(result, o)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+func inRange(value string, gte, lte time.Time) bool {
+	t, ok := parseTime(value)
+	if !ok {
+		return false
+	}
+	return !t.Before(gte) && !t.After(lte)
+}
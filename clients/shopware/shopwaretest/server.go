@@ -0,0 +1,188 @@
+// Package shopwaretest provides a fake Shopware 6 API for development and integration tests.
+package shopwaretest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+)
+
+// Credentials accepted by NewServer.
+const (
+	ClientID     = "shopwaretest-client-id"
+	ClientSecret = "shopwaretest-client-secret"
+)
+
+const (
+	accessToken     = "shopwaretest-access-token"
+	tokenExpiration = 600 // seconds
+)
+
+type handler struct {
+	fixtures     Fixtures
+	clientID     string
+	clientSecret string
+}
+
+// NewServer starts a fake Shopware serving the fixtures and accepting ClientID and ClientSecret.
+// The caller should call Close when finished, to shut it down.
+func NewServer(fixtures Fixtures) *httptest.Server {
+	return httptest.NewServer(NewHandler(fixtures, ClientID, ClientSecret))
+}
+
+// NewHandler serves the endpoints used by the shopware package: the client credentials grant,
+// searches of orders, deliveries and transactions, and products by number.
+func NewHandler(fixtures Fixtures, clientID, clientSecret string) http.Handler {
+	h := handler{
+		fixtures:     fixtures,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/oauth/token", h.token)
+	mux.HandleFunc("/api/v3/search/order", h.authorized(h.searchOrders))
+	mux.HandleFunc("/api/v3/search/order-delivery", h.authorized(h.searchNested("deliveries", "order_delivery")))
+	mux.HandleFunc("/api/v3/search/order-transaction", h.authorized(h.searchNested("transactions", "order_transaction")))
+	mux.HandleFunc("/api/v1/product", h.authorized(h.products))
+	return mux
+}
+
+func (h handler) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+		GrantType    string `json:"grant_type"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.GrantType != "client_credentials" || req.ClientID != h.clientID || req.ClientSecret != h.clientSecret {
+		writeError(w, http.StatusUnauthorized, "invalid client credentials")
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"token_type":   "Bearer",
+		"access_token": accessToken,
+		"expires_in":   tokenExpiration,
+	})
+}
+
+func (h handler) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+accessToken {
+			writeError(w, http.StatusUnauthorized, "invalid access token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (h handler) searchOrders(w http.ResponseWriter, r *http.Request) {
+	h.search(w, r, h.fixtures.Orders, "order")
+}
+
+// searchNested searches entities nested in orders, i.e. deliveries, setting their orderId.
+func (h handler) searchNested(field, entity string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var entities []Entity
+		for _, order := range h.fixtures.Orders {
+			for _, e := range nested(order, field) {
+				item := Entity{"orderId": order["id"]}
+				for k, v := range e {
+					item[k] = v
+				}
+				entities = append(entities, item)
+			}
+		}
+		h.search(w, r, entities, entity)
+	}
+}
+
+func (h handler) search(w http.ResponseWriter, r *http.Request, entities []Entity, entity string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var c criteria
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var matched []Entity
+	for _, e := range entities {
+		ok, err := c.match(e)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if ok {
+			matched = append(matched, e)
+		}
+	}
+	c.sort(matched)
+
+	data := []Entity{}
+	for _, e := range c.paginate(matched) {
+		data = append(data, c.project(e, entity))
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"total": len(matched),
+		"data":  data,
+	})
+}
+
+// products supports the filter[product.productNumber]=... query only.
+func (h handler) products(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var data []Entity
+	number := r.URL.Query().Get("filter[product.productNumber]")
+	for _, p := range h.fixtures.Products {
+		if number == "" || fmt.Sprint(p["productNumber"]) == number {
+			data = append(data, p)
+		}
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"total": len(data),
+		"data":  data,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeError responds in the format of Shopware API errors.
+func writeError(w http.ResponseWriter, status int, detail string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]string{{
+			"status": strconv.Itoa(status),
+			"title":  strings.ToUpper(http.StatusText(status)),
+			"detail": detail,
+		}},
+	})
+}
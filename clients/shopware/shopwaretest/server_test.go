@@ -0,0 +1,215 @@
+package shopwaretest_test
+
+import (
+	"context"
+	"github.com/go-resty/resty/v2"
+	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
+	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware/shopwaretest"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	swsource "github.com/nikolayk812/shopware-orders-scanner/sources/shopware"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var day = time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+
+func newOrderService(t *testing.T, includes shopware.Includes, orders ...shopware.Order) shopware.OrderService {
+	t.Helper()
+
+	fixtures, err := shopwaretest.FixturesFromOrders(orders...)
+	if err != nil {
+		t.Fatalf("shopwaretest.FixturesFromOrders: %v", err)
+	}
+	server := shopwaretest.NewServer(fixtures)
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	client := resty.New().SetHostURL(server.URL)
+	provider, err := shopware.NewCredTokenProvider(ctx, client, shopwaretest.ClientID, shopwaretest.ClientSecret)
+	if err != nil {
+		t.Fatalf("shopware.NewCredTokenProvider: %v", err)
+	}
+	return shopware.NewOrderService(client, provider, includes)
+}
+
+func ids(orders []shopware.Order) []string {
+	result := []string{}
+	for _, o := range orders {
+		result = append(result, o.ID)
+	}
+	return result
+}
+
+func TestServer_SearchByTimeRange(t *testing.T) {
+	service := newOrderService(t, nil,
+		shopwaretest.NewOrder(shopwaretest.ID(1)).CreatedAt(day).UpdatedAt(day.AddDate(0, 0, 3)).Build(),
+		shopwaretest.NewOrder(shopwaretest.ID(2)).CreatedAt(day.AddDate(0, 0, 1)).UpdatedAt(day.AddDate(0, 0, 1)).Build(),
+		shopwaretest.NewOrder(shopwaretest.ID(3)).CreatedAt(day.AddDate(0, 0, 2)).UpdatedAt(day.AddDate(0, 0, 2)).Build(),
+		shopwaretest.NewOrder(shopwaretest.ID(4)).CreatedAt(day.AddDate(0, 0, 3)).UpdatedAt(day.AddDate(0, 0, 3)).Build(),
+	)
+
+	tests := []struct {
+		name     string
+		field    string
+		gte, lte time.Time
+		afterID  string
+		want     []string
+	}{
+		{"created within range", "createdAt", day.AddDate(0, 0, 1), day.AddDate(0, 0, 2), "", []string{shopwaretest.ID(2), shopwaretest.ID(3)}},
+		{"range bounds are inclusive", "createdAt", day, day.AddDate(0, 0, 3), "",
+			[]string{shopwaretest.ID(1), shopwaretest.ID(2), shopwaretest.ID(3), shopwaretest.ID(4)}},
+		{"updated within range", "updatedAt", day.AddDate(0, 0, 3), day.AddDate(0, 0, 4), "", []string{shopwaretest.ID(1), shopwaretest.ID(4)}},
+		{"after id", "createdAt", day, day.AddDate(0, 0, 3), shopwaretest.ID(2), []string{shopwaretest.ID(3), shopwaretest.ID(4)}},
+		{"empty range", "createdAt", day.AddDate(0, 0, 5), day.AddDate(0, 0, 6), "", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders, err := service.SearchByTimeRange(context.Background(), tt.field, tt.gte, tt.lte, tt.afterID)
+			if err != nil {
+				t.Fatalf("SearchByTimeRange: %v", err)
+			}
+			if got := ids(orders); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_EqualsAny(t *testing.T) {
+	service := newOrderService(t, nil,
+		shopwaretest.NewOrder(shopwaretest.ID(1)).Number("10001").Build(),
+		shopwaretest.NewOrder(shopwaretest.ID(2)).Number("10002").State(shopware.OrderStateInProgress).Build(),
+		shopwaretest.NewOrder(shopwaretest.ID(3)).Number("10003").State(shopware.OrderStateDone).Build(),
+	)
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		search func() ([]shopware.Order, error)
+		want   []string
+	}{
+		{"ids", func() ([]shopware.Order, error) {
+			return service.SearchByIDs(ctx, []string{shopwaretest.ID(1), shopwaretest.ID(3), shopwaretest.ID(9)})
+		}, []string{shopwaretest.ID(1), shopwaretest.ID(3)}},
+		{"numbers", func() ([]shopware.Order, error) {
+			return service.SearchByNumbers(ctx, []string{"10002", "99999"})
+		}, []string{shopwaretest.ID(2)}},
+		{"states", func() ([]shopware.Order, error) {
+			return service.SearchByStates(ctx, []shopware.OrderState{shopware.OrderStateOpen, shopware.OrderStateInProgress}, "")
+		}, []string{shopwaretest.ID(1), shopwaretest.ID(2)}},
+		{"states after id", func() ([]shopware.Order, error) {
+			return service.SearchByStates(ctx, []shopware.OrderState{shopware.OrderStateOpen, shopware.OrderStateInProgress}, shopwaretest.ID(1))
+		}, []string{shopwaretest.ID(2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders, err := tt.search()
+			if err != nil {
+				t.Fatalf("search: %v", err)
+			}
+			if got := ids(orders); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_Includes(t *testing.T) {
+	order := shopwaretest.NewOrder(shopwaretest.ID(1)).
+		Number("10001").
+		SalesChannel("channel").
+		Delivery(shopwaretest.NewDelivery(shopware.OrderDeliveryStateShipped).TrackingCodes("code")).
+		Transaction(shopwaretest.NewTransaction(shopware.OrderTransactionStatePaid)).
+		Build()
+
+	t.Run("pruned", func(t *testing.T) {
+		service := newOrderService(t, shopware.Includes{
+			shopware.EntityOrder:         {"orderNumber", "deliveries"},
+			shopware.EntityOrderDelivery: {"trackingCodes"},
+		}, order)
+
+		orders, err := service.SearchByIDs(context.Background(), []string{order.ID})
+		if err != nil {
+			t.Fatalf("SearchByIDs: %v", err)
+		}
+		if len(orders) != 1 {
+			t.Fatalf("got %d orders, want 1", len(orders))
+		}
+		got := orders[0]
+		if got.ID != order.ID || got.Number != "10001" {
+			t.Errorf("got id [%s] and number [%s], want included ones", got.ID, got.Number)
+		}
+		if got.SalesChannelID != "" || len(got.Transactions) != 0 || got.StateMachineState.Name != "" {
+			t.Errorf("got fields which are not included: %+v", got)
+		}
+		if len(got.Deliveries) != 1 || !reflect.DeepEqual(got.Deliveries[0].TrackingCodes, []string{"code"}) {
+			t.Fatalf("got deliveries %+v, want the included tracking codes", got.Deliveries)
+		}
+		if got.Deliveries[0].StateMachineState.Name != "" {
+			t.Errorf("got delivery state [%s] which is not included", got.Deliveries[0].StateMachineState.Name)
+		}
+	})
+
+	t.Run("all", func(t *testing.T) {
+		service := newOrderService(t, nil, order)
+
+		orders, err := service.SearchByIDs(context.Background(), []string{order.ID})
+		if err != nil {
+			t.Fatalf("SearchByIDs: %v", err)
+		}
+		if len(orders) != 1 {
+			t.Fatalf("got %d orders, want 1", len(orders))
+		}
+		got := orders[0]
+		if got.SalesChannelID != "channel" || len(got.Transactions) != 1 || len(got.Deliveries) != 1 ||
+			got.Deliveries[0].StateMachineState.Name != shopware.OrderDeliveryStateShipped {
+			t.Errorf("got %+v, want all fields", got)
+		}
+	})
+}
+
+// TestSource_Paging pages by ids through more orders than fit into a single search.
+func TestSource_Paging(t *testing.T) {
+	const count = 2*shopware.MaxSearchLimit + 1
+
+	var orders []shopware.Order
+	for i := 1; i <= count; i++ {
+		orders = append(orders, shopwaretest.NewOrder(shopwaretest.ID(i)).
+			UpdatedAt(day).
+			Delivery(shopwaretest.NewDelivery(shopware.OrderDeliveryStateShipped).UpdatedAt(day)).
+			Build())
+	}
+	source := swsource.NewSource(newOrderService(t, nil, orders...), 2)
+
+	tests := []struct {
+		name string
+		req  sources.FilterRequest
+	}{
+		{"orders", sources.FilterRequest{From: day, To: day, IncludeUpdated: true}},
+		{"orders of deliveries", sources.FilterRequest{From: day, To: day, IncludeDeliveryUpdated: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := make(chan sources.Page, count)
+			if err := source.Fetch(context.Background(), tt.req, out); err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			close(out)
+
+			seen, rows, pages := map[string]bool{}, 0, 0
+			for page := range out {
+				pages++
+				rows += page.Rows
+				for _, o := range page.Orders {
+					seen[o.ID] = true
+				}
+			}
+			if pages != 3 || rows != count || len(seen) != count {
+				t.Errorf("got %d pages of %d rows and %d distinct orders, want 3 pages of %d", pages, rows, len(seen), count)
+			}
+		})
+	}
+}
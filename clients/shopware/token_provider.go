@@ -9,22 +9,27 @@ import (
 	"time"
 )
 
+// TokenPath is the endpoint of the client credentials grant.
+const TokenPath = "/api/oauth/token"
+
 type TokenProvider interface {
 	GetToken() string
 }
 
-func NewCredTokenProvider(client *resty.Client, clientID, clientSecret string) (TokenProvider, error) {
+// NewCredTokenProvider gets a token and keeps refreshing it until ctx is done.
+func NewCredTokenProvider(ctx context.Context, client *resty.Client, clientID, clientSecret string) (TokenProvider, error) {
 	c := &credTokenProvider{
 		clientID:     clientID,
 		clientSecret: clientSecret,
 		client:       client,
 	}
 
-	if _, err := c.updateToken(context.Background()); err != nil {
+	tokenResp, err := c.updateToken(ctx)
+	if err != nil {
 		return nil, fmt.Errorf("updateToken failed: %w", err)
 	}
 
-	go c.scheduleUpdateToken()
+	go c.scheduleUpdateToken(ctx, tokenResp)
 
 	return c, nil
 }
@@ -45,16 +50,25 @@ func (c *credTokenProvider) GetToken() string {
 	return c.activeAccessToken
 }
 
-func (c *credTokenProvider) scheduleUpdateToken() {
+func (c *credTokenProvider) scheduleUpdateToken(ctx context.Context, tokenResp tokenResponse) {
+	// 0.9 factor should refrain from requests being failed due to expired token
+	delay := time.Duration(tokenResp.ExpiresIn*9/10) * time.Second
 	for {
-		tokenResp, err := c.updateToken(context.Background())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		tokenResp, err := c.updateToken(ctx)
 		if err != nil {
-			zap.S().Errorf("updateToken : %v", err)
-			time.Sleep(time.Second)
+			if ctx.Err() == nil {
+				zap.L().Error("failed to refresh token", zap.String("shop", c.client.HostURL), zap.Error(err))
+			}
+			delay = time.Second
 			continue
 		}
-		// 0.9 factor should refrain from requests being failed due to expired token
-		time.Sleep(time.Duration(tokenResp.ExpiresIn*9/10) * time.Second)
+		delay = time.Duration(tokenResp.ExpiresIn*9/10) * time.Second
 	}
 }
 
@@ -64,14 +78,12 @@ func (c *credTokenProvider) updateToken(ctx context.Context) (tokenResponse, err
 		ClientSecret: c.clientSecret,
 		GrantType:    "client_credentials",
 	}
-	path := "/api/oauth/token"
-
 	var result tokenResponse
 	resp, err := c.client.R().
 		SetContext(ctx).
 		SetBody(request).
 		SetResult(&result).
-		Post(path)
+		Post(TokenPath)
 
 	if err := checkHttpResp(resp, err); err != nil {
 		return tokenResponse{}, err
@@ -4,8 +4,19 @@ import (
 	"fmt"
 	"github.com/go-resty/resty/v2"
 	"net/http"
+	"strings"
 )
 
+// IsWrite tells whether the request changes data of the shop, searches are POST requests which only read.
+func IsWrite(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	path := req.URL.Path
+	return path != TokenPath && !strings.HasPrefix(path, "/api/search/") && !strings.HasPrefix(path, "/api/v3/search/")
+}
+
 func checkHttpResp(resp *resty.Response, err error, expectedStatus ...int) error {
 	if resp == nil {
 		return fmt.Errorf("no response. err: %w", err)
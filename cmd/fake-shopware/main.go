@@ -0,0 +1,38 @@
+// Command fake-shopware serves a fake Shopware 6 API seeded from JSON fixtures for local demos:
+//
+//	go run ./cmd/fake-shopware -addr :8080 -rebase
+//
+// then run the scanner with SHOPWARE_BASE_URL=http://localhost:8080 and the credentials passed here.
+package main
+
+import (
+	"flag"
+	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware/shopwaretest"
+	"log"
+	"net/http"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	fixturesPath := flag.String("fixtures", "clients/shopware/shopwaretest/testdata/fixtures.json", "JSON fixtures file")
+	clientID := flag.String("client-id", shopwaretest.ClientID, "accepted client id")
+	clientSecret := flag.String("client-secret", shopwaretest.ClientSecret, "accepted client secret")
+	rebase := flag.Bool("rebase", false, "shift fixture timestamps so the latest one is yesterday noon")
+	flag.Parse()
+
+	fixtures, err := shopwaretest.LoadFixtures(*fixturesPath)
+	if err != nil {
+		log.Fatalf("shopwaretest.LoadFixtures: %v", err)
+	}
+
+	if *rebase {
+		now := time.Now().UTC()
+		fixtures.Rebase(time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, time.UTC).AddDate(0, 0, -1))
+	}
+
+	log.Printf("serving %d orders and %d products on %s", len(fixtures.Orders), len(fixtures.Products), *addr)
+	if err := http.ListenAndServe(*addr, shopwaretest.NewHandler(fixtures, *clientID, *clientSecret)); err != nil {
+		log.Fatalf("http.ListenAndServe: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+// Command fake-vault serves secrets of a JSON file by path like the KV version 2 engine of Vault, for local demos:
+//
+//	go run ./cmd/fake-vault -addr :8200 -token dev-token -secrets secrets.json
+//
+// where secrets.json is i.e. {"secret/data/shopware": {"client_secret": "..."}},
+// then run the scanner with VAULT_ADDR=http://localhost:8200, VAULT_TOKEN=dev-token
+// and SHOPWARE_CLIENT_SECRET=vault://secret/data/shopware#client_secret.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+)
+
+func main() {
+	addr := flag.String("addr", ":8200", "listen address")
+	token := flag.String("token", "dev-token", "accepted X-Vault-Token")
+	secretsPath := flag.String("secrets", "secrets.json", "JSON file of secrets by path")
+	flag.Parse()
+
+	data, err := ioutil.ReadFile(*secretsPath)
+	if err != nil {
+		log.Fatalf("ioutil.ReadFile: %v", err)
+	}
+	var secrets map[string]map[string]string
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		log.Fatalf("json.Unmarshal: %v", err)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("X-Vault-Token") != *token {
+			writeJSON(w, http.StatusForbidden, map[string]interface{}{"errors": []string{"permission denied"}})
+			return
+		}
+		secret, ok := secrets[strings.TrimPrefix(r.URL.Path, "/v1/")]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{"data": secret, "metadata": map[string]interface{}{"version": 1}},
+		})
+	})
+
+	// only paths are logged, never secrets
+	log.Printf("serving %d secret paths on %s", len(secrets), *addr)
+	if err := http.ListenAndServe(*addr, handler); err != nil {
+		log.Fatalf("http.ListenAndServe: %v", err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("json.Encode: %v", err)
+	}
+}
@@ -6,16 +6,17 @@ import (
 	"github.com/subosito/gotenv"
 	"go.uber.org/zap"
 	"os"
+	"time"
 )
 
 // Parse optionally reads from the file if it exists and sets environment variables if they're unset
-// Then reading from the env vars parses them into the Config.
+// Then reading from the env vars parses them into the Config and resolves its secrets.
 func Parse(configFile string, v interface{}) error {
 	switch info, err := os.Stat(configFile); {
 	case os.IsNotExist(err):
-		zap.S().Warnf("file [%s] doesn't exist", configFile)
+		zap.S().Warnw("config file doesn't exist", "file", configFile)
 	case info.IsDir():
-		zap.S().Warnf("file [%s] is directory", configFile)
+		zap.S().Warnw("config file is directory", "file", configFile)
 	default:
 		if err := gotenv.Load(configFile); err != nil {
 			return fmt.Errorf("gotenv.Load [%s]: %w", configFile, err)
@@ -26,22 +27,94 @@ func Parse(configFile string, v interface{}) error {
 	if err != nil {
 		return fmt.Errorf("envconfig.Process: %w", err)
 	}
+	if err := resolveSecrets("", v); err != nil {
+		return fmt.Errorf("resolveSecrets: %w", err)
+	}
 	return nil
 
 }
 
 type Shopware struct {
-	BaseURL      string `envconfig:"SHOPWARE_BASE_URL" required:"true"`
-	ClientID     string `envconfig:"SHOPWARE_CLIENT_ID" required:"true"`
-	ClientSecret string `envconfig:"SHOPWARE_CLIENT_SECRET" required:"true"`
+	BaseURL      string `envconfig:"SHOPWARE_BASE_URL" required:"true" yaml:"baseUrl"`
+	ClientID     string `envconfig:"SHOPWARE_CLIENT_ID" required:"true" yaml:"clientId"`
+	ClientSecret Secret `envconfig:"SHOPWARE_CLIENT_SECRET" yaml:"clientSecret"` // or SHOPWARE_CLIENT_SECRET_FILE
 }
 
+// SendGrid sends the report to Recipients if any, otherwise to ToEmail.
 type SendGrid struct {
-	Enabled   bool   `envconfig:"SENDGRID_ENABLED" default:"false"`
-	APIKey    string `envconfig:"SENDGRID_API_KEY" required:"false"`
-	ToEmail   string `envconfig:"SENDGRID_TO_EMAIL" required:"false"`
-	ToName    string `envconfig:"SENDGRID_TO_NAME" required:"false"`
-	Subject   string `envconfig:"SENDGRID_SUBJECT" required:"false"`
-	FromEmail string `envconfig:"SENDGRID_FROM_EMAIL" required:"false"`
-	FromName  string `envconfig:"SENDGRID_FROM_NAME" required:"false"`
+	Enabled    bool        `envconfig:"SENDGRID_ENABLED" default:"false" yaml:"enabled"`
+	APIKey     Secret      `envconfig:"SENDGRID_API_KEY" required:"false" yaml:"apiKey"`
+	ToEmail    string      `envconfig:"SENDGRID_TO_EMAIL" required:"false" yaml:"toEmail"`
+	ToName     string      `envconfig:"SENDGRID_TO_NAME" required:"false" yaml:"toName"`
+	Subject    string      `envconfig:"SENDGRID_SUBJECT" required:"false" yaml:"subject"`
+	FromEmail  string      `envconfig:"SENDGRID_FROM_EMAIL" required:"false" yaml:"fromEmail"`
+	FromName   string      `envconfig:"SENDGRID_FROM_NAME" required:"false" yaml:"fromName"`
+	Recipients []Recipient `ignored:"true" yaml:"recipients"`
+}
+
+type Recipient struct {
+	Email string `yaml:"email"`
+	Name  string `yaml:"name"`
+}
+
+// Scan tunes scans and sweeps, which exit with code 1 if more than ExitThreshold failures of ExitSeverity or higher are detected.
+type Scan struct {
+	Parallelism   int           `envconfig:"SCAN_PARALLELISM" default:"4"`
+	WatermarkDir  string        `envconfig:"SCAN_WATERMARK_DIR" default:"watermarks"`
+	Overlap       time.Duration `envconfig:"SCAN_OVERLAP" default:"15m"`
+	ExitSeverity  string        `envconfig:"SCAN_EXIT_SEVERITY" default:"high"`
+	ExitThreshold int           `envconfig:"SCAN_EXIT_THRESHOLD" default:"0"`
+}
+
+// Findings is the file keeping findings of scans and sweeps for the dashboard.
+type Findings struct {
+	Path string `envconfig:"FINDINGS_PATH" default:"findings.json"`
+}
+
+// Serve schedules runs of the serve command by cron expressions, an empty schedule disables the run.
+type Serve struct {
+	ScanSchedule  string `envconfig:"SERVE_SCAN_SCHEDULE" default:"0 6 * * *"`
+	SweepSchedule string `envconfig:"SERVE_SWEEP_SCHEDULE" default:"0 7 * * 1"`
+	TimeZone      string `envconfig:"SERVE_TIMEZONE" default:"Europe/Berlin"`
+}
+
+// API is served by the serve command if Addr is set, Token authorizes its callers.
+type API struct {
+	Addr  string `envconfig:"API_ADDR" default:""`
+	Token Secret `envconfig:"API_TOKEN" required:"false"`
+}
+
+// Dashboard is served by the serve command if Addr is set.
+type Dashboard struct {
+	Addr string `envconfig:"DASHBOARD_ADDR" default:""`
+}
+
+// Metrics are served at Addr by the serve command and pushed to PushgatewayURL by scan and sweep if set.
+type Metrics struct {
+	Addr           string `envconfig:"METRICS_ADDR" default:""`
+	PushgatewayURL string `envconfig:"METRICS_PUSHGATEWAY_URL" default:""`
+	Job            string `envconfig:"METRICS_JOB" default:"shopware_orders_scanner"`
+}
+
+// Tracing exports spans over OTLP gRPC to Endpoint, i.e. a local OpenTelemetry collector.
+// SampleRatio is the share of traced runs, CheckSampleRatio the share of traced order evaluations within them.
+type Tracing struct {
+	Enabled          bool    `envconfig:"TRACING_ENABLED" default:"false"`
+	Endpoint         string  `envconfig:"TRACING_OTLP_ENDPOINT" default:"localhost:55680"`
+	Insecure         bool    `envconfig:"TRACING_OTLP_INSECURE" default:"true"`
+	SampleRatio      float64 `envconfig:"TRACING_SAMPLE_RATIO" default:"1"`
+	CheckSampleRatio float64 `envconfig:"TRACING_CHECK_SAMPLE_RATIO" default:"0.01"`
+}
+
+// Log configures the process logger, Output is stdout, stderr or a file path.
+type Log struct {
+	Format string `envconfig:"LOG_FORMAT" default:"console"`
+	Level  string `envconfig:"LOG_LEVEL" default:"info"`
+	Output string `envconfig:"LOG_OUTPUT" default:"stdout"`
+}
+
+// DryRun runs the whole pipeline but logs writes instead of performing them and dumps notifications into Dir instead of sending them.
+type DryRun struct {
+	Enabled bool   `envconfig:"DRY_RUN" default:"false"`
+	Dir     string `envconfig:"DRY_RUN_DIR" default:"dry-run"`
 }
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Profile of a shop: how to access it, which checks to run and how to report findings.
+type Profile struct {
+	Name          string            `yaml:"-"`
+	Shopware      Shopware          `yaml:"shopware"`
+	SendGrid      SendGrid          `yaml:"sendgrid"`
+	SalesChannels map[string]string `yaml:"salesChannels"` // names by id, shown in reports instead of ids
+	Checks        []string          `yaml:"checks"`        // enabled rules, all if empty
+	Consumers     []string          `yaml:"consumers"`     // mail and html, mail if SendGrid is enabled and html otherwise if empty
+}
+
+// Report combines results of all profiles run together, delivered by its consumers, html if empty.
+type Report struct {
+	SendGrid  SendGrid `yaml:"sendgrid"`
+	Consumers []string `yaml:"consumers"`
+}
+
+// Shops of a config file.
+type Shops struct {
+	Profiles []Profile // sorted by name
+	Report   Report
+}
+
+type shopsFile struct {
+	Profiles map[string]Profile `yaml:"profiles"`
+	Report   Report             `yaml:"report"`
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// LoadShops reads shop profiles and their combined report from a YAML file.
+// Environment variables prefixed by the upper-cased profile name override its values,
+// i.e. DE_SHOPWARE_CLIENT_SECRET or DE_SHOPWARE_CLIENT_SECRET_FILE the client secret of the de profile,
+// REPORT_ ones those of the report.
+func LoadShops(path string) (Shops, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Shops{}, fmt.Errorf("ioutil.ReadFile [%s] : %w", path, err)
+	}
+
+	var file shopsFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return Shops{}, fmt.Errorf("yaml.Unmarshal [%s] : %w", path, err)
+	}
+	if len(file.Profiles) == 0 {
+		return Shops{}, fmt.Errorf("no profiles in [%s]", path)
+	}
+
+	shops := Shops{Report: file.Report}
+	if err := override("REPORT_", &shops.Report.SendGrid); err != nil {
+		return Shops{}, fmt.Errorf("report : %w", err)
+	}
+	if err := resolveSecrets("REPORT_", &shops.Report); err != nil {
+		return Shops{}, fmt.Errorf("report : %w", err)
+	}
+
+	for name, p := range file.Profiles {
+		p.Name = name
+		prefix := nonAlphanumeric.ReplaceAllString(strings.ToUpper(name), "_") + "_"
+		if err := override(prefix, &p.Shopware); err != nil {
+			return Shops{}, fmt.Errorf("profile [%s] : %w", name, err)
+		}
+		if err := override(prefix, &p.SendGrid); err != nil {
+			return Shops{}, fmt.Errorf("profile [%s] : %w", name, err)
+		}
+		if err := resolveSecrets(prefix, &p); err != nil {
+			return Shops{}, fmt.Errorf("profile [%s] : %w", name, err)
+		}
+
+		if p.Shopware.BaseURL == "" || p.Shopware.ClientID == "" || p.Shopware.ClientSecret == "" {
+			return Shops{}, fmt.Errorf("profile [%s] : shopware baseUrl, clientId and clientSecret are required", name)
+		}
+		shops.Profiles = append(shops.Profiles, p)
+	}
+
+	sort.Slice(shops.Profiles, func(i, j int) bool {
+		return shops.Profiles[i].Name < shops.Profiles[j].Name
+	})
+	return shops, nil
+}
+
+// override sets string and bool fields of the struct pointed to by v from environment variables
+// named by the prefix and their envconfig tags.
+func override(prefix string, v interface{}) error {
+	s := reflect.ValueOf(v).Elem()
+	for i := 0; i < s.NumField(); i++ {
+		tag := s.Type().Field(i).Tag.Get("envconfig")
+		if tag == "" {
+			continue
+		}
+		name := prefix + tag
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		switch f := s.Field(i); f.Kind() {
+		case reflect.String:
+			f.SetString(value)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s [%s] : %w", name, value, err)
+			}
+			f.SetBool(b)
+		default:
+			return fmt.Errorf("unsupported type %s of %s", f.Kind(), name)
+		}
+	}
+	return nil
+}
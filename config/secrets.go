@@ -0,0 +1,181 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Secret is a config value never to be logged, it's printed redacted by the fmt package.
+// Its environment variable NAME can be replaced by NAME_FILE, the path of a file holding the secret,
+// and its value can reference a secret of a resolver, i.e. vault://secret/data/shop#client_secret.
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "[REDACTED]"
+}
+
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+// SecretResolver resolves references of its scheme, i.e. /run/secrets/key of file:///run/secrets/key.
+// Errors must not contain the secret.
+type SecretResolver interface {
+	Resolve(ref string) (string, error)
+}
+
+var resolvers = map[string]SecretResolver{
+	"file":  fileResolver{},
+	"env":   envResolver{},
+	"vault": vaultResolver{client: &http.Client{Timeout: 10 * time.Second}},
+}
+
+// RegisterResolver makes secret values prefixed by scheme:// resolved by r, it's not safe to call concurrently with Parse.
+func RegisterResolver(scheme string, r SecretResolver) {
+	resolvers[scheme] = r
+}
+
+var secretType = reflect.TypeOf(Secret(""))
+
+// resolveSecrets replaces Secret fields of the struct pointed to by v, including embedded ones,
+// by contents of the files named by their prefixed _FILE environment variables and resolves their references.
+func resolveSecrets(prefix string, v interface{}) error {
+	return resolveStruct(prefix, reflect.ValueOf(v).Elem())
+}
+
+func resolveStruct(prefix string, s reflect.Value) error {
+	for i := 0; i < s.NumField(); i++ {
+		field, f := s.Type().Field(i), s.Field(i)
+		if field.Tag.Get("ignored") == "true" || !f.CanSet() {
+			continue
+		}
+		if f.Kind() == reflect.Struct {
+			if err := resolveStruct(prefix, f); err != nil {
+				return err
+			}
+			continue
+		}
+		tag := field.Tag.Get("envconfig")
+		if field.Type != secretType || tag == "" {
+			continue
+		}
+
+		value, err := resolveSecret(prefix+tag, f.String())
+		if err != nil {
+			return err
+		}
+		f.SetString(value)
+	}
+	return nil
+}
+
+func resolveSecret(name, value string) (string, error) {
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		secret, err := fileResolver{}.Resolve(path)
+		if err != nil {
+			return "", fmt.Errorf("%s_FILE : %w", name, err)
+		}
+		value = secret
+	}
+
+	i := strings.Index(value, "://")
+	if i < 0 {
+		return value, nil
+	}
+	scheme, ref := value[:i], value[i+len("://"):]
+	r, ok := resolvers[scheme]
+	if !ok {
+		return value, nil
+	}
+	secret, err := r.Resolve(ref)
+	if err != nil {
+		return "", fmt.Errorf("%s : %s resolver : %w", name, scheme, err)
+	}
+	return secret, nil
+}
+
+// fileResolver reads the secret from the file at ref, i.e. a mounted Kubernetes secret, without trailing line breaks.
+type fileResolver struct{}
+
+func (fileResolver) Resolve(ref string) (string, error) {
+	data, err := ioutil.ReadFile(ref)
+	if err != nil {
+		return "", fmt.Errorf("ioutil.ReadFile [%s] : %w", ref, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// envResolver reads the secret from the environment variable named ref.
+type envResolver struct{}
+
+func (envResolver) Resolve(ref string) (string, error) {
+	secret, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("%s is not set", ref)
+	}
+	return secret, nil
+}
+
+// vaultResolver reads key of a path#key ref from a Vault compatible KV secrets engine, version 1 or 2,
+// at VAULT_ADDR authorized by VAULT_TOKEN or the contents of VAULT_TOKEN_FILE.
+type vaultResolver struct {
+	client *http.Client
+}
+
+func (r vaultResolver) Resolve(ref string) (string, error) {
+	i := strings.LastIndex(ref, "#")
+	if i < 0 {
+		return "", fmt.Errorf("no key in [%s], expected path#key", ref)
+	}
+	path, key := strings.Trim(ref[:i], "/"), ref[i+1:]
+
+	addr := strings.TrimRight(os.Getenv("VAULT_ADDR"), "/")
+	if addr == "" {
+		return "", fmt.Errorf("VAULT_ADDR is not set")
+	}
+	token, err := resolveSecret("VAULT_TOKEN", os.Getenv("VAULT_TOKEN"))
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, addr+"/v1/"+path, nil)
+	if err != nil {
+		return "", fmt.Errorf("http.NewRequest : %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("client.Do : %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response code %d for [%s]", resp.StatusCode, path)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("json.Decode [%s] : %w", path, err)
+	}
+	data := body.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		// KV version 2 nests the secret next to its metadata
+		data = nested
+	}
+	secret, ok := data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("no string key [%s] in [%s]", key, path)
+	}
+	return secret, nil
+}
@@ -0,0 +1,185 @@
+package config_test
+
+import (
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/config"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	secret     = "s3cret-value"
+	vaultToken = "vault-token"
+)
+
+// variables are set or unset by every test and restored afterwards.
+var variables = []string{"API_TOKEN", "API_TOKEN_FILE", "VAULT_ADDR", "VAULT_TOKEN", "VAULT_TOKEN_FILE", "SECRETS_TEST_TOKEN"}
+
+func setenv(t *testing.T, vars map[string]string) {
+	t.Helper()
+
+	for _, name := range variables {
+		name := name
+		old, ok := os.LookupEnv(name)
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(name, old)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+
+		if value, set := vars[name]; set {
+			os.Setenv(name, value)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("ioutil.WriteFile: %v", err)
+	}
+	return path
+}
+
+// newVault serves KV version 1 secrets at secret/v1/shop and version 2 ones at secret/data/shop to vaultToken only.
+func newVault(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != vaultToken {
+			http.Error(w, `{"errors": ["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/v1/shop":
+			fmt.Fprintf(w, `{"data": {"token": %q, "port": 8080}}`, secret)
+		case "/v1/secret/data/shop":
+			fmt.Fprintf(w, `{"data": {"data": {"token": %q}, "metadata": {"version": 3}}}`, secret)
+		case "/v1/secret/data/broken":
+			fmt.Fprint(w, `{"data": `)
+		default:
+			http.Error(w, `{"errors": []}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestParse_Secrets(t *testing.T) {
+	vault := newVault(t)
+	dir := tempDir(t)
+	tokenFile := writeFile(t, dir, "token", secret+"\n")
+	vaultTokenFile := writeFile(t, dir, "vault-token", vaultToken+"\r\n")
+	refFile := writeFile(t, dir, "ref", "vault://secret/data/shop#token\n")
+
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		want    string
+		wantErr string
+	}{
+		{name: "plain value", vars: map[string]string{"API_TOKEN": secret}, want: secret},
+		{name: "unset", vars: map[string]string{}, want: ""},
+		{name: "unknown scheme kept", vars: map[string]string{"API_TOKEN": "https://example.com"}, want: "https://example.com"},
+		{name: "_FILE without line breaks", vars: map[string]string{"API_TOKEN_FILE": tokenFile}, want: secret},
+		{name: "_FILE over the value", vars: map[string]string{"API_TOKEN": "other", "API_TOKEN_FILE": tokenFile}, want: secret},
+		{name: "_FILE holding a reference", vars: map[string]string{"API_TOKEN_FILE": refFile,
+			"VAULT_ADDR": vault.URL, "VAULT_TOKEN": vaultToken}, want: secret},
+		{name: "file reference", vars: map[string]string{"API_TOKEN": "file://" + tokenFile}, want: secret},
+		{name: "env reference", vars: map[string]string{"API_TOKEN": "env://SECRETS_TEST_TOKEN", "SECRETS_TEST_TOKEN": secret}, want: secret},
+		{name: "vault KV v1", vars: map[string]string{"API_TOKEN": "vault://secret/v1/shop#token",
+			"VAULT_ADDR": vault.URL, "VAULT_TOKEN": vaultToken}, want: secret},
+		{name: "vault KV v2, slashes trimmed", vars: map[string]string{"API_TOKEN": "vault:///secret/data/shop/#token",
+			"VAULT_ADDR": vault.URL + "/", "VAULT_TOKEN": vaultToken}, want: secret},
+		{name: "vault token from a file", vars: map[string]string{"API_TOKEN": "vault://secret/data/shop#token",
+			"VAULT_ADDR": vault.URL, "VAULT_TOKEN_FILE": vaultTokenFile}, want: secret},
+
+		{name: "missing _FILE", vars: map[string]string{"API_TOKEN_FILE": filepath.Join(dir, "missing")},
+			wantErr: "API_TOKEN_FILE : ioutil.ReadFile"},
+		{name: "unset env reference", vars: map[string]string{"API_TOKEN": "env://SECRETS_TEST_TOKEN"},
+			wantErr: "API_TOKEN : env resolver : SECRETS_TEST_TOKEN is not set"},
+		{name: "vault ref without key", vars: map[string]string{"API_TOKEN": "vault://secret/data/shop",
+			"VAULT_ADDR": vault.URL, "VAULT_TOKEN": vaultToken}, wantErr: "no key in [secret/data/shop], expected path#key"},
+		{name: "vault address unset", vars: map[string]string{"API_TOKEN": "vault://secret/data/shop#token",
+			"VAULT_TOKEN": vaultToken}, wantErr: "VAULT_ADDR is not set"},
+		{name: "vault missing key", vars: map[string]string{"API_TOKEN": "vault://secret/data/shop#password",
+			"VAULT_ADDR": vault.URL, "VAULT_TOKEN": vaultToken}, wantErr: "no string key [password] in [secret/data/shop]"},
+		{name: "vault key of another type", vars: map[string]string{"API_TOKEN": "vault://secret/v1/shop#port",
+			"VAULT_ADDR": vault.URL, "VAULT_TOKEN": vaultToken}, wantErr: "no string key [port] in [secret/v1/shop]"},
+		{name: "vault path not found", vars: map[string]string{"API_TOKEN": "vault://secret/data/missing#token",
+			"VAULT_ADDR": vault.URL, "VAULT_TOKEN": vaultToken}, wantErr: "unexpected response code 404 for [secret/data/missing]"},
+		{name: "vault token denied", vars: map[string]string{"API_TOKEN": "vault://secret/data/shop#token",
+			"VAULT_ADDR": vault.URL, "VAULT_TOKEN": "wrong-" + vaultToken}, wantErr: "unexpected response code 403 for [secret/data/shop]"},
+		{name: "vault malformed response", vars: map[string]string{"API_TOKEN": "vault://secret/data/broken#token",
+			"VAULT_ADDR": vault.URL, "VAULT_TOKEN": vaultToken}, wantErr: "json.Decode [secret/data/broken]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, tt.vars)
+
+			var cfg config.API
+			err := config.Parse(filepath.Join(dir, "missing.env"), &cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error [%v], want one containing [%s]", err, tt.wantErr)
+				}
+				// the secrets never make it into the error, however it's formatted
+				for _, formatted := range []string{err.Error(), fmt.Sprintf("%+v", err), fmt.Sprintf("%#v", err)} {
+					if strings.Contains(formatted, secret) || strings.Contains(formatted, vaultToken) {
+						t.Errorf("got a secret in error [%s]", formatted)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			if got := string(cfg.Token); got != tt.want {
+				t.Errorf("got token [%s], want [%s]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecret_Redacted(t *testing.T) {
+	cfg := config.API{Addr: ":8080", Token: secret}
+	wrapped := fmt.Errorf("failed with %v : %w", cfg, fmt.Errorf("token %s", cfg.Token))
+
+	for _, formatted := range []string{
+		fmt.Sprint(cfg.Token),
+		fmt.Sprintf("%v %+v %#v %s", cfg, cfg, cfg, cfg.Token),
+		fmt.Sprintf("%q", cfg.Token),
+		wrapped.Error(),
+	} {
+		if strings.Contains(formatted, secret) || !strings.Contains(formatted, "[REDACTED]") {
+			t.Errorf("got [%s], want the secret redacted", formatted)
+		}
+	}
+
+	if got := config.Secret("").String(); got != "" {
+		t.Errorf("got [%s] for an empty secret, want it empty", got)
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"go.uber.org/multierr"
+	"net/mail"
+	"net/url"
+)
+
+// Validate checks access to Shopware without connecting to it.
+func (c Shopware) Validate() error {
+	var err error
+	if c.BaseURL == "" {
+		err = multierr.Append(err, errors.New("SHOPWARE_BASE_URL is required"))
+	} else if urlErr := validateURL(c.BaseURL); urlErr != nil {
+		err = multierr.Append(err, fmt.Errorf("SHOPWARE_BASE_URL %w", urlErr))
+	}
+	if c.ClientID == "" {
+		err = multierr.Append(err, errors.New("SHOPWARE_CLIENT_ID is required"))
+	}
+	if c.ClientSecret == "" {
+		err = multierr.Append(err, errors.New("SHOPWARE_CLIENT_SECRET is required"))
+	}
+	return err
+}
+
+// Validate checks fields required to send emails if enabled.
+func (c SendGrid) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	var err error
+	if c.APIKey == "" {
+		err = multierr.Append(err, errors.New("SENDGRID_API_KEY is required"))
+	}
+	if c.FromEmail == "" {
+		err = multierr.Append(err, errors.New("SENDGRID_FROM_EMAIL is required"))
+	} else if _, addrErr := mail.ParseAddress(c.FromEmail); addrErr != nil {
+		err = multierr.Append(err, fmt.Errorf("SENDGRID_FROM_EMAIL [%s] : %w", c.FromEmail, addrErr))
+	}
+
+	if len(c.Recipients) == 0 {
+		if c.ToEmail == "" {
+			err = multierr.Append(err, errors.New("SENDGRID_TO_EMAIL or recipients are required"))
+		} else if _, addrErr := mail.ParseAddress(c.ToEmail); addrErr != nil {
+			err = multierr.Append(err, fmt.Errorf("SENDGRID_TO_EMAIL [%s] : %w", c.ToEmail, addrErr))
+		}
+	}
+	for _, r := range c.Recipients {
+		if _, addrErr := mail.ParseAddress(r.Email); addrErr != nil {
+			err = multierr.Append(err, fmt.Errorf("recipient [%s] : %w", r.Email, addrErr))
+		}
+	}
+	return err
+}
+
+// Validate checks the Pushgateway URL if set.
+func (c Metrics) Validate() error {
+	if c.PushgatewayURL == "" {
+		return nil
+	}
+	if err := validateURL(c.PushgatewayURL); err != nil {
+		return fmt.Errorf("METRICS_PUSHGATEWAY_URL %w", err)
+	}
+	return nil
+}
+
+// Validate checks the endpoint and sample ratios if tracing is enabled.
+func (c Tracing) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	var err error
+	if c.Endpoint == "" {
+		err = multierr.Append(err, errors.New("TRACING_OTLP_ENDPOINT is required"))
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		err = multierr.Append(err, fmt.Errorf("TRACING_SAMPLE_RATIO [%v] is not within [0, 1]", c.SampleRatio))
+	}
+	if c.CheckSampleRatio < 0 || c.CheckSampleRatio > 1 {
+		err = multierr.Append(err, fmt.Errorf("TRACING_CHECK_SAMPLE_RATIO [%v] is not within [0, 1]", c.CheckSampleRatio))
+	}
+	return err
+}
+
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("[%s] : %w", raw, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("[%s] is not an absolute http or https URL", raw)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/config"
+	"github.com/nikolayk812/shopware-orders-scanner/orders"
+	swsource "github.com/nikolayk812/shopware-orders-scanner/sources/shopware"
+	"go.uber.org/multierr"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// checkTimeout bounds connecting to a shop by config check.
+const checkTimeout = 30 * time.Second
+
+// runConfig runs subcommands of config, check is the only one.
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return configError(errors.New("usage: config check [-config shops.yaml] [-profile name]"))
+	}
+	return runConfigCheck(args[1:])
+}
+
+// runConfigCheck validates the whole configuration, then gets a token and searches orders, deliveries and transactions
+// updated within the last minute of every selected shop to verify connectivity and permissions. Nothing is reported.
+func runConfigCheck(args []string) error {
+	flags := flag.NewFlagSet("config check", flag.ContinueOnError)
+	pf := addProfileFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return configError(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	var problems error
+	check := func(name string, err error) error {
+		status := "ok"
+		if err != nil {
+			status = "FAILED " + err.Error()
+			problems = multierr.Append(problems, fmt.Errorf("%s : %w", name, err))
+		}
+		fmt.Fprintf(w, "%s\t%s\n", name, status)
+		return err
+	}
+	// validate runs once v has been parsed
+	parse := func(v interface{}, validate func() error) error {
+		if err := config.Parse("local.env", v); err != nil {
+			return fmt.Errorf("config.Parse: %w", err)
+		}
+		return validate()
+	}
+
+	var cfg mainConfig
+	check("scan", parse(&cfg, func() error { return validateExit(cfg.Scan) }))
+	var serveCfg config.Serve
+	check("serve", parse(&serveCfg, func() error { return validateServe(serveCfg) }))
+	var apiCfg config.API
+	check("api", parse(&apiCfg, func() error {
+		if apiCfg.Addr != "" && apiCfg.Token == "" {
+			return errors.New("API_TOKEN is required to serve the API")
+		}
+		return nil
+	}))
+	var metricsCfg config.Metrics
+	check("metrics", parse(&metricsCfg, func() error { return metricsCfg.Validate() }))
+	var tracingCfg config.Tracing
+	check("tracing", parse(&tracingCfg, func() error { return tracingCfg.Validate() }))
+	check("templates", validateTemplates())
+	shops, err := pf.load()
+	check("profiles", err)
+	if problems != nil {
+		return configError(problems)
+	}
+
+	problems = nil
+	for _, p := range shops.Profiles {
+		shop := p.Name
+		if shop == "" {
+			shop = p.Shopware.BaseURL
+		}
+		checkShop(shop, p, check)
+	}
+	if problems != nil {
+		return sourceError(problems)
+	}
+	return nil
+}
+
+// checkShop gets a token and searches with the includes of the enabled checks, as scans do.
+func checkShop(shop string, p config.Profile, check func(string, error) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	includes := swsource.Includes(append(buildEngine(p.Checks).Fields(), orders.Fields...))
+	orderCli, _, err := buildShopwareClients(ctx, p.Shopware, includes)
+	if check(shop+" token", err) != nil {
+		return
+	}
+
+	to := time.Now()
+	from := to.Add(-time.Minute)
+	_, err = orderCli.SearchByTimeRange(ctx, "updatedAt", from, to, "")
+	check(shop+" orders", err)
+	_, err = orderCli.SearchDeliveriesByTimeRange(ctx, "updatedAt", from, to, "")
+	check(shop+" deliveries", err)
+	_, err = orderCli.SearchTransactionsByTimeRange(ctx, "updatedAt", from, to, "")
+	check(shop+" transactions", err)
+}
@@ -11,23 +11,28 @@ import (
 type Renderer struct {
 	templatePath    string
 	shopwareBaseURL string
+	channels        map[string]string
 }
 
-func NewRenderer(templatePath, shopwareBaseURL string) Renderer {
+// NewRenderer shows sales channels by their names in channels, by ids if not found.
+func NewRenderer(templatePath, shopwareBaseURL string, channels map[string]string) Renderer {
 	return Renderer{
 		templatePath:    templatePath,
 		shopwareBaseURL: shopwareBaseURL,
+		channels:        channels,
 	}
 }
 
 func (r Renderer) Consume(orders []domain.OrderResult, scanned int) (consumers.Result, error) {
 	params := struct {
 		BaseURL  string
+		Channels map[string]string
 		Orders   []domain.OrderResult
 		Scanned  int
 		Detected int
 	}{
 		BaseURL:  r.shopwareBaseURL,
+		Channels: r.channels,
 		Orders:   orders,
 		Scanned:  scanned,
 		Detected: len(orders),
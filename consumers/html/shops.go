@@ -0,0 +1,54 @@
+package html
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/consumers"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	"html/template"
+)
+
+// Shop section of a combined report, Err is set if the shop has failed to be scanned or reported.
+type Shop struct {
+	Name     string
+	BaseURL  string
+	Channels map[string]string
+	Orders   []domain.OrderResult
+	Scanned  int
+	Err      error
+}
+
+// ShopsRenderer renders results of several shops grouped per shop, linking orders to their shop's admin.
+type ShopsRenderer struct {
+	templatePath string
+}
+
+func NewShopsRenderer(templatePath string) ShopsRenderer {
+	return ShopsRenderer{templatePath: templatePath}
+}
+
+func (r ShopsRenderer) Consume(shops []Shop) (consumers.Result, error) {
+	params := struct {
+		Shops    []Shop
+		Scanned  int
+		Detected int
+	}{
+		Shops: shops,
+	}
+	for _, s := range shops {
+		params.Scanned += s.Scanned
+		params.Detected += len(s.Orders)
+	}
+
+	t, err := template.ParseFiles(r.templatePath)
+	if err != nil {
+		return consumers.Result{}, fmt.Errorf("template.ParseFiles [%s] : %w", r.templatePath, err)
+	}
+
+	var body bytes.Buffer
+	if err := t.Execute(&body, params); err != nil {
+		return consumers.Result{}, fmt.Errorf("t.Execute : %w", err)
+	}
+
+	return consumers.Result{Bytes: body.Bytes()}, nil
+}
@@ -14,10 +14,10 @@ type Sender struct {
 	renderer html.Renderer
 }
 
-func NewSender(swConf config.Shopware, sgConf config.SendGrid) Sender {
+func NewSender(profile config.Profile) Sender {
 	return Sender{
-		sender:   ms.NewSender(sgConf),
-		renderer: html.NewRenderer("./consumers/html/template.twig", swConf.BaseURL),
+		sender:   ms.NewSender(profile.SendGrid),
+		renderer: html.NewRenderer("./consumers/html/template.twig", profile.Shopware.BaseURL, profile.SalesChannels),
 	}
 }
 
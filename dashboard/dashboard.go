@@ -0,0 +1,233 @@
+// Package dashboard renders the findings store as HTML pages: open findings filterable and sortable
+// by rule, sales channel, severity and age, and daily trends of detected and open findings.
+package dashboard
+
+import (
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/checks"
+	"github.com/nikolayk812/shopware-orders-scanner/findings"
+	"go.uber.org/zap"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	trendDays   = 30
+	chartHeight = 100
+	barWidth    = 20
+)
+
+var severityRank = map[checks.Severity]int{
+	checks.SeverityHigh:   0,
+	checks.SeverityMedium: 1,
+	checks.SeverityLow:    2,
+}
+
+type handler struct {
+	store        findings.Store
+	templatePath string
+	channels     map[string]string
+}
+
+// NewHandler serves the dashboard at "/", the template is parsed on every request.
+// Sales channels are shown and filtered by their names in channels, by ids if not found.
+func NewHandler(store findings.Store, templatePath string, channels map[string]string) http.Handler {
+	h := handler{store: store, templatePath: templatePath, channels: channels}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.index)
+	return mux
+}
+
+// filter of open findings by query parameters, empty ones match all findings.
+type filter struct {
+	Rule     string
+	Channel  string // name, or id of a channel without one
+	Severity checks.Severity
+	MinAge   int // days
+	Sort     string
+}
+
+func (f filter) matches(finding findings.Finding, channel string) bool {
+	return (f.Rule == "" || finding.Rule == f.Rule) &&
+		(f.Channel == "" || channel == f.Channel || finding.ChannelID == f.Channel) &&
+		(f.Severity == "" || finding.Severity == f.Severity)
+}
+
+type row struct {
+	findings.Finding
+	Channel  string
+	AgeDays  int
+	AdminURL string
+}
+
+// bar of a trend chart, in SVG coordinates.
+type bar struct {
+	Day    string
+	Count  int
+	X, Y   int
+	Height int
+}
+
+type page struct {
+	Filter     filter
+	Rows       []row
+	Rules      []string
+	Channels   []string
+	Severities []checks.Severity
+	Detected   []bar
+	Open       []bar
+	Width      int
+	Height     int
+	Now        time.Time
+}
+
+func (h handler) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	q := r.URL.Query()
+	minAge, _ := strconv.Atoi(q.Get("age"))
+	f := filter{
+		Rule:     q.Get("rule"),
+		Channel:  q.Get("channel"),
+		Severity: checks.Severity(q.Get("severity")),
+		MinAge:   minAge,
+		Sort:     q.Get("sort"),
+	}
+
+	all, err := h.store.All()
+	if err != nil {
+		zap.L().Error("failed to read findings", zap.Error(err))
+		http.Error(w, "failed to read findings", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	p := page{
+		Filter:     f,
+		Severities: []checks.Severity{checks.SeverityHigh, checks.SeverityMedium, checks.SeverityLow},
+		Width:      trendDays * barWidth,
+		Height:     chartHeight,
+		Now:        now,
+	}
+
+	rules, channels := map[string]bool{}, map[string]bool{}
+	var matched []findings.Finding
+	for _, finding := range all {
+		rules[finding.Rule] = true
+		channels[h.channel(finding)] = true
+		if f.matches(finding, h.channel(finding)) {
+			matched = append(matched, finding)
+		}
+	}
+	p.Rules, p.Channels = keys(rules), keys(channels)
+
+	for _, finding := range matched {
+		ageDays := int(finding.Age(now).Hours() / 24)
+		if !finding.Open() || ageDays < f.MinAge {
+			continue
+		}
+		p.Rows = append(p.Rows, row{
+			Finding:  finding,
+			Channel:  h.channel(finding),
+			AgeDays:  ageDays,
+			AdminURL: fmt.Sprintf("%s/admin#/sw/order/detail/%s", finding.Shop, finding.OrderID),
+		})
+	}
+	sortRows(p.Rows, f.Sort)
+	p.Detected, p.Open = trends(matched, now)
+
+	t, err := template.ParseFiles(h.templatePath)
+	if err != nil {
+		zap.L().Error("failed to parse template", zap.String("path", h.templatePath), zap.Error(err))
+		http.Error(w, "failed to parse template", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := t.Execute(w, p); err != nil {
+		zap.L().Error("failed to render template", zap.String("path", h.templatePath), zap.Error(err))
+	}
+}
+
+// channel returns the name of the sales channel of the finding, its id if unnamed.
+func (h handler) channel(finding findings.Finding) string {
+	if name, ok := h.channels[finding.ChannelID]; ok && name != "" {
+		return name
+	}
+	return finding.ChannelID
+}
+
+// sortRows sorts by age, oldest first, unless sorted by number, rule or severity.
+func sortRows(rows []row, by string) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		switch by {
+		case "number":
+			return a.OrderNumber < b.OrderNumber
+		case "rule":
+			return a.Rule < b.Rule
+		case "severity":
+			if severityRank[a.Severity] != severityRank[b.Severity] {
+				return severityRank[a.Severity] < severityRank[b.Severity]
+			}
+		}
+		return a.FirstSeen.Before(b.FirstSeen)
+	})
+}
+
+// trends counts per day of the last trendDays findings detected that day and findings open at its end.
+func trends(all []findings.Finding, now time.Time) ([]bar, []bar) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	detected := make([]bar, trendDays)
+	open := make([]bar, trendDays)
+	for i := 0; i < trendDays; i++ {
+		start := today.AddDate(0, 0, i-trendDays+1)
+		end := start.AddDate(0, 0, 1)
+		day := start.Format("2006-01-02")
+		detected[i].Day, open[i].Day = day, day
+
+		for _, f := range all {
+			if !f.FirstSeen.Before(start) && f.FirstSeen.Before(end) {
+				detected[i].Count++
+			}
+			if f.FirstSeen.Before(end) && (f.Open() || !f.ResolvedAt.Before(end)) {
+				open[i].Count++
+			}
+		}
+	}
+
+	scale(detected)
+	scale(open)
+	return detected, open
+}
+
+// scale fits the bars into the chart height.
+func scale(bars []bar) {
+	max := 1
+	for _, b := range bars {
+		if b.Count > max {
+			max = b.Count
+		}
+	}
+	for i := range bars {
+		bars[i].X = i * barWidth
+		bars[i].Height = bars[i].Count * chartHeight / max
+		bars[i].Y = chartHeight - bars[i].Height
+	}
+}
+
+func keys(m map[string]bool) []string {
+	var result []string
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
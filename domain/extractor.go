@@ -1,30 +1,29 @@
 package domain
 
 import (
-	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
 	"sort"
 )
 
-func FirstDelivery(order shopware.Order) (shopware.OrderDelivery, bool) {
+func FirstDelivery(order Order) (Delivery, bool) {
 	if len(order.Deliveries) == 0 {
-		return shopware.OrderDelivery{}, false
+		return Delivery{}, false
 	}
 
 	return order.Deliveries[0], true
 }
 
-func FirstDocument(order shopware.Order) (shopware.OrderDocument, bool) {
+func FirstDocument(order Order) (Document, bool) {
 	if len(order.Documents) == 0 {
-		return shopware.OrderDocument{}, false
+		return Document{}, false
 	}
 
 	return order.Documents[0], true
 }
 
-func LatestTransaction(order shopware.Order) (shopware.OrderTransaction, bool) {
+func LatestTransaction(order Order) (Transaction, bool) {
 	transactions := order.Transactions
 	if len(transactions) == 0 {
-		return shopware.OrderTransaction{}, false
+		return Transaction{}, false
 	}
 
 	sort.SliceStable(transactions, func(i, j int) bool {
@@ -35,7 +34,7 @@ func LatestTransaction(order shopware.Order) (shopware.OrderTransaction, bool) {
 	return transactions[len(transactions)-1], true
 }
 
-func TrackingCode(order shopware.Order) string {
+func TrackingCode(order Order) string {
 	if len(order.Deliveries) == 0 {
 		return "absent"
 	}
@@ -0,0 +1,92 @@
+package domain
+
+import "time"
+
+// Order is a source-neutral order, checks only see this model.
+type Order struct {
+	ID             string        `json:"id"`
+	Number         string        `json:"number"`
+	SalesChannelID string        `json:"salesChannelId"`
+	State          OrderState    `json:"state"`
+	Deliveries     []Delivery    `json:"deliveries"`
+	Transactions   []Transaction `json:"transactions"`
+	Documents      []Document    `json:"documents"`
+	LineItems      []LineItem    `json:"lineItems"`
+	CreatedAt      time.Time     `json:"createdAt"`
+	UpdatedAt      time.Time     `json:"updatedAt"`
+}
+
+type Delivery struct {
+	ID            string        `json:"id"`
+	State         DeliveryState `json:"state"`
+	TrackingCodes []string      `json:"trackingCodes"`
+	UpdatedAt     time.Time     `json:"updatedAt"`
+}
+
+type Transaction struct {
+	ID        string           `json:"id"`
+	State     TransactionState `json:"state"`
+	CreatedAt time.Time        `json:"createdAt"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+}
+
+type Document struct {
+	FileType string `json:"fileType"`
+	FileName string `json:"fileName"`
+}
+
+type LineItem struct {
+	ProductID     string `json:"productId"`
+	ProductNumber string `json:"productNumber"`
+}
+
+// States are named after Shopware ones, other sources map their states onto them.
+
+type OrderState string
+
+const (
+	OrderStateOpen       OrderState = "Open"
+	OrderStateInProgress OrderState = "In progress"
+	OrderStateDone       OrderState = "Done"
+	OrderStateCancelled  OrderState = "Cancelled"
+)
+
+type TransactionState string
+
+const (
+	TransactionStateOpen              TransactionState = "Open"
+	TransactionStatePaid              TransactionState = "Paid"
+	TransactionStateCancelled         TransactionState = "Cancelled"
+	TransactionStateRefunded          TransactionState = "Refunded"
+	TransactionStateRefundedPartially TransactionState = "Refunded (partially)"
+	TransactionStateReminded          TransactionState = "Reminded"
+	TransactionStateFailed            TransactionState = "Failed"
+	TransactionStateInProgress        TransactionState = "In Progress"
+)
+
+type DeliveryState string
+
+const (
+	DeliveryStateOpen              DeliveryState = "Open"
+	DeliveryStateShipped           DeliveryState = "Shipped"
+	DeliveryStateShippedPartially  DeliveryState = "Shipped (partially)"
+	DeliveryStateCancelled         DeliveryState = "Cancelled"
+	DeliveryStateReturned          DeliveryState = "Returned"
+	DeliveryStateReturnedPartially DeliveryState = "Returned (partially)"
+)
+
+// Field names an order field read by checks, sources may fetch only the requested ones.
+// ID, number, sales channel and timestamps of orders are always available.
+type Field string
+
+const (
+	FieldState                 Field = "state"
+	FieldDeliveryState         Field = "deliveries.state"
+	FieldDeliveryTrackingCodes Field = "deliveries.trackingCodes"
+	FieldDeliveryUpdatedAt     Field = "deliveries.updatedAt"
+	FieldTransactionState      Field = "transactions.state"
+	FieldTransactionCreatedAt  Field = "transactions.createdAt"
+	FieldDocumentFileType      Field = "documents.fileType"
+	FieldDocumentFileName      Field = "documents.fileName"
+	FieldLineItems             Field = "lineItems"
+)
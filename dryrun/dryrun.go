@@ -0,0 +1,99 @@
+// Package dryrun is the read-only safety mode of the process: the whole pipeline runs,
+// but writes are logged instead of performed and outbound notifications are dumped into local files.
+package dryrun
+
+import (
+	"bytes"
+	"fmt"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+)
+
+var (
+	mu      sync.RWMutex
+	enabled bool
+	dumpDir string
+)
+
+// Enable turns the dry run on for the rest of the process, dumps are written into dir.
+func Enable(dir string) {
+	mu.Lock()
+	defer mu.Unlock()
+	enabled, dumpDir = true, dir
+}
+
+// Enabled tells whether writes are suppressed.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return enabled
+}
+
+// Skip logs the intended write if the dry run is enabled and tells the caller to skip it then.
+func Skip(write string, fields ...zap.Field) bool {
+	if !Enabled() {
+		return false
+	}
+	zap.L().Info("dry run, skipped "+write, fields...)
+	return true
+}
+
+// Dump writes content of a suppressed notification into a new file of the dump directory named by pattern,
+// whose last * is replaced by a random string as of ioutil.TempFile, and returns its path.
+func Dump(pattern string, content []byte) (string, error) {
+	mu.RLock()
+	dir := dumpDir
+	mu.RUnlock()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("os.MkdirAll [%s] : %w", dir, err)
+	}
+	f, err := ioutil.TempFile(dir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("ioutil.TempFile : %w", err)
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return "", fmt.Errorf("f.Write : %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("f.Close : %w", err)
+	}
+	return f.Name(), nil
+}
+
+type transport struct {
+	next    http.RoundTripper
+	isWrite func(req *http.Request) bool
+}
+
+// Transport sends requests by next, http.DefaultTransport if nil. While the dry run is enabled,
+// requests isWrite tells apart are logged and answered by 204 No Content without reaching the server.
+func Transport(next http.RoundTripper, isWrite func(req *http.Request) bool) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return transport{next: next, isWrite: isWrite}
+}
+
+func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.isWrite(req) || !Skip("request", zap.String("method", req.Method), zap.String("url", req.URL.String())) {
+		return t.next.RoundTrip(req)
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return &http.Response{
+		Status:     "204 No Content",
+		StatusCode: http.StatusNoContent,
+		Proto:      req.Proto,
+		ProtoMajor: req.ProtoMajor,
+		ProtoMinor: req.ProtoMinor,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+		Request:    req,
+	}, nil
+}
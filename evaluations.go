@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/checks"
+	"github.com/nikolayk812/shopware-orders-scanner/orders"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// stringsFlag collects values of a repeated flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// readLines returns non-empty lines of a file, except for # comments.
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open [%s] : %w", path, err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanner.Scan [%s] : %w", path, err)
+	}
+	return lines, nil
+}
+
+// printEvaluations prints outcomes of every check per order, then the requested orders which were not found.
+func printEvaluations(out io.Writer, req sources.FilterRequest, evaluations []orders.Evaluation) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	found := map[string]bool{}
+	for _, e := range evaluations {
+		found[e.Order.ID] = true
+		found[e.Order.Number] = true
+
+		fmt.Fprintf(w, "order %s [%s] %s\n", e.Order.Number, e.Order.ID, e.Order.State)
+		for _, o := range e.Outcomes {
+			switch {
+			case o.Status == checks.StatusFailed:
+				fmt.Fprintf(w, "\t%s\t%s\t%v\n", o.Status, o.Rule, o.Err)
+			case o.Reason != "":
+				fmt.Fprintf(w, "\t%s\t%s\t%s\n", o.Status, o.Rule, o.Reason)
+			default:
+				fmt.Fprintf(w, "\t%s\t%s\n", o.Status, o.Rule)
+			}
+		}
+	}
+
+	for _, v := range append(req.OrderNumbers, req.OrderIDs...) {
+		if !found[v] {
+			fmt.Fprintf(w, "order %s not found\n", v)
+		}
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/checks"
+	"github.com/nikolayk812/shopware-orders-scanner/config"
+	"github.com/nikolayk812/shopware-orders-scanner/orders"
+)
+
+// Process exit codes, wrapping jobs may page on 2-4 and only notify on 1.
+const (
+	exitClean    = 0
+	exitFindings = 1 // findings above the threshold
+	exitSource   = 2 // Shopware, API or any other unclassified failure
+	exitConsumer = 3 // the report could not be delivered
+	exitConfig   = 4 // invalid configuration, flags or arguments
+)
+
+// exitError carries the exit code of the process failing with err.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e exitError) Unwrap() error {
+	return e.err
+}
+
+func configError(err error) error {
+	return exitError{code: exitConfig, err: err}
+}
+
+func sourceError(err error) error {
+	return exitError{code: exitSource, err: err}
+}
+
+func consumerError(err error) error {
+	return exitError{code: exitConsumer, err: err}
+}
+
+// exitCode returns the code of the outermost exitError wrapped by err.
+func exitCode(err error) int {
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return exitClean
+	}
+
+	var e exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return exitSource
+}
+
+func validateExit(cfg config.Scan) error {
+	if !checks.Severity(cfg.ExitSeverity).Valid() {
+		return configError(fmt.Errorf("invalid SCAN_EXIT_SEVERITY [%s]", cfg.ExitSeverity))
+	}
+	return nil
+}
+
+// checkFindings fails with exitFindings if more than the threshold of failures are at least of the exit severity.
+func checkFindings(cfg config.Scan, result orders.ScanResult) error {
+	min := checks.Severity(cfg.ExitSeverity)
+	severities := buildEngine(nil).Severities()
+
+	count := 0
+	for _, o := range result.Orders {
+		for rule := range o.Errors {
+			if severities[rule].AtLeast(min) {
+				count++
+			}
+		}
+	}
+
+	if count > cfg.ExitThreshold {
+		return exitError{
+			code: exitFindings,
+			err:  fmt.Errorf("%d findings of severity [%s] or higher exceed the threshold of %d", count, min, cfg.ExitThreshold),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/checks"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	"github.com/nikolayk812/shopware-orders-scanner/orders"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	swsource "github.com/nikolayk812/shopware-orders-scanner/sources/shopware"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runExplain prints the state of an order relevant to the checks and why every check passed, skipped or failed.
+func runExplain(args []string) error {
+	flags := flag.NewFlagSet("explain", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: explain [-config shops.yaml -profile name] <orderNumber>")
+		flags.PrintDefaults()
+	}
+	pf := addProfileFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return configError(err)
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return configError(fmt.Errorf("expected a single order number"))
+	}
+	number := flags.Arg(0)
+
+	profile, err := pf.single()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// all fields, not only those read by the checks, are printed
+	orderCli, _, err := buildShopwareClients(ctx, profile.Shopware, nil)
+	if err != nil {
+		return fmt.Errorf("buildShopwareClients : %w", err)
+	}
+
+	source := swsource.NewSource(orderCli, 1)
+	service := orders.NewService(source, buildEngine(profile.Checks), 1)
+	evaluations, err := service.EvaluateOrders(ctx, sources.FilterRequest{OrderNumbers: []string{number}})
+	if err != nil {
+		return sourceError(fmt.Errorf("failed to evaluate order [%s] : %w", number, err))
+	}
+	if len(evaluations) == 0 {
+		// a mistyped number rather than a Shopware failure
+		return configError(fmt.Errorf("order [%s] not found", number))
+	}
+
+	return printExplanation(os.Stdout, evaluations[0])
+}
+
+func printExplanation(out io.Writer, e orders.Evaluation) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	o := e.Order
+
+	fmt.Fprintf(w, "order %s [%s]\n", o.Number, o.ID)
+	fmt.Fprintf(w, "\tstate\t%s\n", o.State)
+	fmt.Fprintf(w, "\tsales channel\t%s\n", o.SalesChannelID)
+	fmt.Fprintf(w, "\tcreated at\t%s\n", formatTime(o.CreatedAt))
+	fmt.Fprintf(w, "\tupdated at\t%s\n", formatTime(o.UpdatedAt))
+
+	if len(o.Deliveries) == 0 {
+		fmt.Fprintf(w, "\tdeliveries\tnone\n")
+	}
+	for i, d := range o.Deliveries {
+		fmt.Fprintf(w, "\tdelivery #%d\t%s, tracking codes [%s], updated at %s\n",
+			i+1, d.State, strings.Join(d.TrackingCodes, ", "), formatTime(d.UpdatedAt))
+	}
+
+	if len(o.Transactions) == 0 {
+		fmt.Fprintf(w, "\ttransactions\tnone\n")
+	}
+	for i, tx := range o.Transactions {
+		fmt.Fprintf(w, "\ttransaction #%d\t%s, created at %s\n", i+1, tx.State, formatTime(tx.CreatedAt))
+	}
+	if tx, ok := domain.LatestTransaction(o); ok {
+		fmt.Fprintf(w, "\tlatest transaction\t%s\n", tx.State)
+	}
+
+	if len(o.Documents) == 0 {
+		fmt.Fprintf(w, "\tdocuments\tnone\n")
+	}
+	for i, doc := range o.Documents {
+		fmt.Fprintf(w, "\tdocument #%d\t%s %s\n", i+1, doc.FileType, doc.FileName)
+	}
+
+	fmt.Fprintf(w, "\nchecks\n")
+	for _, outcome := range e.Outcomes {
+		switch outcome.Status {
+		case checks.StatusPassed:
+			fmt.Fprintf(w, "\t%s\t%s\t%s\n", outcome.Status, outcome.Rule, outcome.Description)
+		case checks.StatusFailed:
+			fmt.Fprintf(w, "\t%s\t%s\t%v, expected: %s\n", outcome.Status, outcome.Rule, outcome.Err, outcome.Description)
+		default:
+			reason := outcome.Reason
+			if reason == "" {
+				reason = "not applicable"
+			}
+			fmt.Fprintf(w, "\t%s\t%s\tpre-condition not met: %s\n", outcome.Status, outcome.Rule, reason)
+		}
+	}
+
+	return w.Flush()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
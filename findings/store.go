@@ -0,0 +1,209 @@
+// Package findings keeps the history of failed checks across scans in a local JSON file.
+//
+// A finding is a rule failed by an order of a shop. It is open from the scan which detected it
+// until a later scan checks the order again and the rule no longer fails.
+package findings
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/checks"
+	"github.com/nikolayk812/shopware-orders-scanner/dryrun"
+	"github.com/nikolayk812/shopware-orders-scanner/orders"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// retention of resolved findings, open ones are kept until resolved
+const retention = 90 * 24 * time.Hour
+
+type Finding struct {
+	Shop        string          `json:"shop"`
+	OrderID     string          `json:"orderId"`
+	OrderNumber string          `json:"orderNumber"`
+	ChannelID   string          `json:"channelId"`
+	Rule        string          `json:"rule"`
+	Severity    checks.Severity `json:"severity"`
+	Message     string          `json:"message"`
+	FirstSeen   time.Time       `json:"firstSeen"`
+	LastSeen    time.Time       `json:"lastSeen"`
+	ResolvedAt  *time.Time      `json:"resolvedAt,omitempty"`
+}
+
+func (f Finding) Open() bool {
+	return f.ResolvedAt == nil
+}
+
+// Age is the time since the finding has been detected.
+func (f Finding) Age(now time.Time) time.Duration {
+	return now.Sub(f.FirstSeen)
+}
+
+func (f Finding) key() string {
+	return f.Shop + "/" + f.OrderID + "/" + f.Rule
+}
+
+// Store reads the file on every call, so findings recorded by other processes are visible right away.
+// Records into the same file are serialized within a process only: a single process may record into it,
+// i.e. serve or cron runs of scan and sweep, but not both sharing FINDINGS_PATH.
+type Store struct {
+	path string
+	mu   *sync.Mutex
+}
+
+// locks by path, shared by stores of the same file, i.e. of shops scanned concurrently
+var (
+	locksMu sync.Mutex
+	locks   = map[string]*sync.Mutex{}
+)
+
+func NewStore(path string) Store {
+	locksMu.Lock()
+	defer locksMu.Unlock()
+
+	mu, ok := locks[path]
+	if !ok {
+		mu = &sync.Mutex{}
+		locks[path] = mu
+	}
+	return Store{path: path, mu: mu}
+}
+
+// All returns open and resolved findings of all shops.
+func (s Store) All() ([]Finding, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+// OpenOrderIDs returns ids of orders with open findings of the shop, to be watched by the next scan.
+func (s Store) OpenOrderIDs(shop string) (map[string]bool, error) {
+	all, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[string]bool{}
+	for _, f := range all {
+		if f.Open() && f.Shop == shop {
+			ids[f.OrderID] = true
+		}
+	}
+	return ids, nil
+}
+
+// Record opens findings for the failures of the scan, resolves open findings of the rechecked orders which no longer fail
+// and forgets findings resolved longer than the retention ago.
+func (s Store) Record(shop string, at time.Time, result orders.ScanResult, severities map[string]checks.Severity) error {
+	if dryrun.Skip("findings record", zap.String("shop", shop), zap.Int("suspicious", len(result.Orders))) {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	open := map[string]int{}
+	for i, f := range all {
+		if f.Open() {
+			open[f.key()] = i
+		}
+	}
+
+	failing := map[string]bool{}
+	for _, o := range result.Orders {
+		for rule, ruleErr := range o.Errors {
+			f := Finding{
+				Shop:        shop,
+				OrderID:     o.OrderID,
+				OrderNumber: o.OrderNumber,
+				ChannelID:   o.ChannelID,
+				Rule:        rule,
+				Severity:    severities[rule],
+				Message:     ruleErr.Error(),
+				FirstSeen:   at,
+				LastSeen:    at,
+			}
+			failing[f.key()] = true
+
+			if i, ok := open[f.key()]; ok {
+				all[i].LastSeen, all[i].Message, all[i].Severity = at, f.Message, f.Severity
+				continue
+			}
+			all = append(all, f)
+		}
+	}
+
+	rechecked := map[string]bool{}
+	for _, id := range result.Rechecked {
+		rechecked[id] = true
+	}
+
+	var kept []Finding
+	for _, f := range all {
+		if f.Open() && f.Shop == shop && rechecked[f.OrderID] && !failing[f.key()] {
+			resolvedAt := at
+			f.ResolvedAt = &resolvedAt
+		}
+		if !f.Open() && at.Sub(*f.ResolvedAt) > retention {
+			continue
+		}
+		kept = append(kept, f)
+	}
+
+	return s.write(kept)
+}
+
+func (s Store) read() ([]Finding, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ioutil.ReadFile : %w", err)
+	}
+
+	var all []Finding
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal [%s] : %w", s.path, err)
+	}
+	return all, nil
+}
+
+// write replaces the file atomically, so concurrent readers never see it truncated.
+func (s Store) write(all []Finding) error {
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent : %w", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("os.MkdirAll [%s] : %w", dir, err)
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".findings-*")
+	if err != nil {
+		return fmt.Errorf("ioutil.TempFile : %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("tmp.Write : %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("tmp.Close : %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("os.Rename : %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package findings_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/checks"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	"github.com/nikolayk812/shopware-orders-scanner/findings"
+	"github.com/nikolayk812/shopware-orders-scanner/orders"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	shop      = "https://shop.example.com"
+	otherShop = "https://other.example.com"
+)
+
+var (
+	day        = time.Date(2020, 10, 1, 6, 0, 0, 0, time.UTC)
+	severities = map[string]checks.Severity{"TRACKING_CODE": checks.SeverityHigh}
+)
+
+func tempPath(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "findings")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "findings.json")
+}
+
+// record is a scan of the shop the given number of days after the first one, failing and rechecking orders by ids.
+type record struct {
+	shop      string
+	day       int
+	failing   []string
+	rechecked []string
+}
+
+func (r record) result() orders.ScanResult {
+	result := orders.ScanResult{Rechecked: r.rechecked}
+	for _, id := range r.failing {
+		result.Orders = append(result.Orders, domain.OrderResult{
+			OrderID:     id,
+			OrderNumber: "1000" + id,
+			Errors:      map[string]error{"TRACKING_CODE": errors.New("no tracking code")},
+		})
+	}
+	return result
+}
+
+// describe returns findings as "shop order first-last resolved" with days after the first scan.
+func describe(all []findings.Finding) []string {
+	days := func(t time.Time) int { return int(t.Sub(day).Hours() / 24) }
+
+	result := []string{}
+	for _, f := range all {
+		resolved := "open"
+		if !f.Open() {
+			resolved = fmt.Sprintf("resolved %d", days(*f.ResolvedAt))
+		}
+		s := strings.TrimPrefix(f.Shop, "https://")
+		result = append(result, fmt.Sprintf("%s %s %d-%d %s", s, f.OrderID, days(f.FirstSeen), days(f.LastSeen), resolved))
+	}
+	return result
+}
+
+func TestStore_Record(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []record
+		want    []string
+	}{
+		{"opened", []record{
+			{shop: shop, day: 0, failing: []string{"1", "2"}},
+		}, []string{"shop.example.com 1 0-0 open", "shop.example.com 2 0-0 open"}},
+		{"still failing", []record{
+			{shop: shop, day: 0, failing: []string{"1"}},
+			{shop: shop, day: 1, failing: []string{"1"}, rechecked: []string{"1"}},
+		}, []string{"shop.example.com 1 0-1 open"}},
+		{"resolved once rechecked and passed", []record{
+			{shop: shop, day: 0, failing: []string{"1", "2"}},
+			{shop: shop, day: 1, rechecked: []string{"1"}},
+		}, []string{"shop.example.com 1 0-0 resolved 1", "shop.example.com 2 0-0 open"}},
+		{"not resolved by another shop", []record{
+			{shop: shop, day: 0, failing: []string{"1"}},
+			{shop: otherShop, day: 1, rechecked: []string{"1"}},
+		}, []string{"shop.example.com 1 0-0 open"}},
+		{"reopened as a new finding", []record{
+			{shop: shop, day: 0, failing: []string{"1"}},
+			{shop: shop, day: 1, rechecked: []string{"1"}},
+			{shop: shop, day: 2, failing: []string{"1"}, rechecked: []string{"1"}},
+		}, []string{"shop.example.com 1 0-0 resolved 1", "shop.example.com 1 2-2 open"}},
+		{"resolved ones forgotten after the retention", []record{
+			{shop: shop, day: 0, failing: []string{"1", "2"}},
+			{shop: shop, day: 1, rechecked: []string{"1"}},
+			{shop: shop, day: 92},
+		}, []string{"shop.example.com 2 0-0 open"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tempPath(t)
+			for _, r := range tt.records {
+				if err := findings.NewStore(path).Record(r.shop, day.AddDate(0, 0, r.day), r.result(), severities); err != nil {
+					t.Fatalf("Record: %v", err)
+				}
+			}
+
+			all, err := findings.NewStore(path).All()
+			if err != nil {
+				t.Fatalf("All: %v", err)
+			}
+			if got := describe(all); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStore_OpenOrderIDs(t *testing.T) {
+	path := tempPath(t)
+	store := findings.NewStore(path)
+	for _, r := range []record{
+		{shop: shop, day: 0, failing: []string{"1", "2"}},
+		{shop: otherShop, day: 0, failing: []string{"3"}},
+		{shop: shop, day: 1, failing: []string{"2"}, rechecked: []string{"1", "2"}},
+	} {
+		if err := store.Record(r.shop, day.AddDate(0, 0, r.day), r.result(), severities); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	got, err := store.OpenOrderIDs(shop)
+	if err != nil {
+		t.Fatalf("OpenOrderIDs: %v", err)
+	}
+	if want := map[string]bool{"2": true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestStore_Write leaves only the findings file behind, written through a temporary one.
+func TestStore_Write(t *testing.T) {
+	path := tempPath(t)
+	if err := findings.NewStore(path).Record(shop, day, record{failing: []string{"1"}}.result(), severities); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	files, err := ioutil.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("ioutil.ReadDir: %v", err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	if want := []string{"findings.json"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got files %v, want %v", names, want)
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	path := tempPath(t)
+	if err := ioutil.WriteFile(path, []byte("[{"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile: %v", err)
+	}
+	store := findings.NewStore(path)
+
+	if _, err := store.All(); err == nil || !strings.Contains(err.Error(), "json.Unmarshal") {
+		t.Errorf("All: got error [%v], want json.Unmarshal one", err)
+	}
+	if err := store.Record(shop, day, orders.ScanResult{}, severities); err == nil {
+		t.Error("Record: got no error, want one for the malformed file")
+	}
+}
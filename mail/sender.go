@@ -3,8 +3,11 @@ package mail
 import (
 	"fmt"
 	"github.com/nikolayk812/shopware-orders-scanner/config"
+	"github.com/nikolayk812/shopware-orders-scanner/dryrun"
 	"github.com/sendgrid/sendgrid-go"
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
+	"go.uber.org/zap"
+	"time"
 )
 
 type Sender struct {
@@ -22,10 +25,23 @@ func (s Sender) SendMail(htmlContent string) error {
 		return nil
 	}
 
-	from := mail.NewEmail(s.conf.FromName, s.conf.FromEmail)
-	to := mail.NewEmail(s.conf.ToName, s.conf.ToEmail)
-	message := mail.NewSingleEmail(from, s.conf.Subject, to, "Shopware Orders Scanner", htmlContent)
-	client := sendgrid.NewSendClient(s.conf.APIKey)
+	message := mail.NewV3Mail()
+	message.SetFrom(mail.NewEmail(s.conf.FromName, s.conf.FromEmail))
+	message.Subject = s.conf.Subject
+	message.AddPersonalizations(s.recipients())
+	message.AddContent(mail.NewContent("text/plain", "Shopware Orders Scanner"), mail.NewContent("text/html", htmlContent))
+
+	if dryrun.Enabled() {
+		// the request body SendGrid would have got, without the API key
+		path, err := dryrun.Dump("mail_"+time.Now().Format("01-02-2006_15:04:05")+"_*.json", mail.GetRequestBody(message))
+		if err != nil {
+			return fmt.Errorf("dryrun.Dump : %w", err)
+		}
+		zap.L().Info("dry run, dumped mail instead of sending it", zap.String("subject", s.conf.Subject), zap.String("path", path))
+		return nil
+	}
+
+	client := sendgrid.NewSendClient(string(s.conf.APIKey))
 
 	resp, err := client.Send(message)
 	if err != nil {
@@ -37,3 +53,16 @@ func (s Sender) SendMail(htmlContent string) error {
 
 	return nil
 }
+
+// recipients addresses every recipient in a single email.
+func (s Sender) recipients() *mail.Personalization {
+	p := mail.NewPersonalization()
+	if len(s.conf.Recipients) == 0 {
+		p.AddTos(mail.NewEmail(s.conf.ToName, s.conf.ToEmail))
+		return p
+	}
+	for _, r := range s.conf.Recipients {
+		p.AddTos(mail.NewEmail(r.Name, r.Email))
+	}
+	return p
+}
@@ -0,0 +1,144 @@
+// Package metrics exposes Prometheus metrics of scanner runs, their findings and Shopware requests,
+// served over HTTP by long running processes and pushed to a Pushgateway by one-shot ones.
+package metrics
+
+import (
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/dryrun"
+	"github.com/nikolayk812/shopware-orders-scanner/orders"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+const namespace = "shopware_orders_scanner"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	runs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "runs_total",
+		Help:      "Scans and sweeps of a shop by outcome.",
+	}, []string{"run", "shop", "status"})
+
+	runDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "run_duration_seconds",
+		Help:      "Duration of scans and sweeps, failed ones included.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{"run", "shop"})
+
+	lastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful scan or sweep of a shop.",
+	}, []string{"run", "shop"})
+
+	scannedOrders = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scanned_orders_total",
+		Help:      "Distinct orders checked by scans and sweeps.",
+	}, []string{"run", "shop"})
+
+	findings = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "findings",
+		Help:      "Orders failing a rule detected by the last scan or sweep of a shop.",
+	}, []string{"run", "shop", "rule"})
+
+	openFindings = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "open_findings",
+		Help:      "Open findings of the findings store by severity.",
+	}, []string{"severity"})
+
+	shopwareRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "shopware_requests_total",
+		Help:      "Shopware API requests by endpoint and status code, 0 if no response was received.",
+	}, []string{"endpoint", "code"})
+
+	shopwareErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "shopware_request_errors_total",
+		Help:      "Shopware API requests without a response or with a 4xx or 5xx one.",
+	}, []string{"endpoint"})
+
+	shopwareLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "shopware_request_duration_seconds",
+		Help:      "Latency of Shopware API requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	tokenRefreshFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_refresh_failures_total",
+		Help:      "Failed requests of a Shopware access token.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		runs, runDuration, lastSuccess, scannedOrders, findings, openFindings,
+		shopwareRequests, shopwareErrors, shopwareLatency, tokenRefreshFailures,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves all metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Push replaces metrics of the job on the Pushgateway at url.
+func Push(url, job string) error {
+	if dryrun.Skip("metrics push", zap.String("url", url), zap.String("job", job)) {
+		return nil
+	}
+	if err := push.New(url, job).Gatherer(registry).Push(); err != nil {
+		return fmt.Errorf("push.Push [%s] : %w", url, err)
+	}
+	return nil
+}
+
+// ObserveRun records the outcome and duration of a scan or sweep of the shop at its base URL.
+func ObserveRun(run, shop string, start time.Time, err error) {
+	runDuration.WithLabelValues(run, shop).Observe(time.Since(start).Seconds())
+	if err != nil {
+		runs.WithLabelValues(run, shop, "failed").Inc()
+		return
+	}
+	runs.WithLabelValues(run, shop, "succeeded").Inc()
+	lastSuccess.WithLabelValues(run, shop).SetToCurrentTime()
+}
+
+// ObserveResult records scanned orders and failures per rule, rules without failures are reset to 0.
+func ObserveResult(run, shop string, result orders.ScanResult, rules []string) {
+	scannedOrders.WithLabelValues(run, shop).Add(float64(result.Scanned))
+
+	counts := map[string]int{}
+	for _, rule := range rules {
+		counts[rule] = 0
+	}
+	for _, o := range result.Orders {
+		for rule := range o.Errors {
+			counts[rule]++
+		}
+	}
+	for rule, count := range counts {
+		findings.WithLabelValues(run, shop, rule).Set(float64(count))
+	}
+}
+
+// SetOpenFindings records the number of open findings by severity.
+func SetOpenFindings(counts map[string]int) {
+	for severity, count := range counts {
+		openFindings.WithLabelValues(severity).Set(float64(count))
+	}
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type transport struct {
+	next http.RoundTripper
+}
+
+// Transport instruments Shopware API requests sent by next, http.DefaultTransport if nil.
+// Endpoints are request paths, which are a handful of fixed ones for the Shopware client.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return transport{next: next}
+}
+
+func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := req.URL.Path
+	start := time.Now()
+
+	resp, err := t.next.RoundTrip(req)
+	shopwareLatency.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+
+	code := 0
+	if resp != nil {
+		code = resp.StatusCode
+	}
+	shopwareRequests.WithLabelValues(endpoint, strconv.Itoa(code)).Inc()
+
+	if err != nil || code >= http.StatusBadRequest {
+		shopwareErrors.WithLabelValues(endpoint).Inc()
+		if endpoint == shopware.TokenPath {
+			tokenRefreshFailures.Inc()
+		}
+	}
+	return resp, err
+}
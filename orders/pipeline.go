@@ -0,0 +1,161 @@
+package orders
+
+import (
+	"context"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/checks"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	"github.com/nikolayk812/shopware-orders-scanner/tracing"
+	"go.opentelemetry.io/otel/label"
+	"golang.org/x/sync/errgroup"
+	"sync"
+)
+
+// pipeline streams orders through fetch -> dedup -> check -> collect stages connected by bounded channels,
+// so a slow stage blocks the previous ones and at most a few pages of orders are held in memory.
+type pipeline struct {
+	service   Service
+	processed *processedSet
+}
+
+func newPipeline(s Service) *pipeline {
+	return &pipeline{
+		service:   s,
+		processed: newProcessedSet(),
+	}
+}
+
+// Evaluation is the outcome of all checks of an order.
+type Evaluation struct {
+	Order    domain.Order
+	Outcomes []checks.Outcome
+}
+
+// run passes evaluations to collect one by one, result has no orders.
+func (p *pipeline) run(ctx context.Context, req sources.FilterRequest, collect func(Evaluation)) (ScanResult, error) {
+	g, ctx := errgroup.WithContext(ctx)
+
+	fetched := make(chan sources.Page, p.service.parallelism)
+	unique := make(chan domain.Order, p.service.parallelism)
+	evaluated := make(chan Evaluation, p.service.parallelism)
+
+	g.Go(func() error {
+		defer close(fetched)
+		return p.service.source.Fetch(ctx, req, fetched)
+	})
+
+	var result ScanResult
+	g.Go(func() error {
+		defer close(unique)
+		return p.dedup(ctx, fetched, unique, &result)
+	})
+
+	g.Go(func() error {
+		defer close(evaluated)
+		return p.check(ctx, unique, evaluated)
+	})
+
+	g.Go(func() error {
+		for e := range evaluated {
+			collect(e)
+		}
+		return nil
+	})
+
+	if err := g.Wait(); err != nil {
+		return ScanResult{}, err
+	}
+
+	result.Scanned = p.processed.len()
+	return result, nil
+}
+
+// dedup passes on only orders seen for the first time, recording them if there is a recorder.
+// It counts pages and rows into the result.
+func (p *pipeline) dedup(ctx context.Context, in <-chan sources.Page, out chan<- domain.Order, result *ScanResult) error {
+	for page := range in {
+		result.Pages++
+		result.Rows += page.Rows
+
+		for _, order := range page.Orders {
+			if !p.processed.add(order.ID) {
+				continue
+			}
+			if p.service.recorder != nil {
+				if err := p.service.recorder.Record(order); err != nil {
+					return fmt.Errorf("recorder.Record : %w", err)
+				}
+			}
+			select {
+			case out <- order:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
+
+// check runs the checks engine on parallelism workers.
+func (p *pipeline) check(ctx context.Context, in <-chan domain.Order, out chan<- Evaluation) error {
+	g, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < p.service.parallelism; i++ {
+		g.Go(func() error {
+			for order := range in {
+				e := Evaluation{Order: order, Outcomes: p.evaluate(ctx, order)}
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// evaluate traces a sample of evaluations only.
+func (p *pipeline) evaluate(ctx context.Context, order domain.Order) []checks.Outcome {
+	if !tracing.SampleCheck() {
+		return p.service.engine.Evaluate(order)
+	}
+
+	ctx, span := tracing.Start(ctx, "Engine.Evaluate", label.String("orderId", order.ID), label.String("orderNumber", order.Number))
+	outcomes := p.service.engine.Evaluate(order)
+	for _, o := range outcomes {
+		span.SetAttributes(label.String("rule."+o.Rule, string(o.Status)))
+	}
+	tracing.End(ctx, span, nil)
+	return outcomes
+}
+
+// processedSet is a concurrency-safe set of ids of already checked orders.
+type processedSet struct {
+	mu  sync.Mutex
+	ids map[string]bool // guarded by mu
+}
+
+func newProcessedSet() *processedSet {
+	return &processedSet{ids: map[string]bool{}}
+}
+
+// add returns false if the id has been added before.
+func (p *processedSet) add(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ids[id] {
+		return false
+	}
+	p.ids[id] = true
+	return true
+}
+
+func (p *processedSet) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.ids)
+}
@@ -4,156 +4,152 @@ import (
 	"context"
 	"fmt"
 	"github.com/nikolayk812/shopware-orders-scanner/checks"
-	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
 	"github.com/nikolayk812/shopware-orders-scanner/domain"
-	"go.uber.org/zap"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	"github.com/nikolayk812/shopware-orders-scanner/tracing"
+	"go.opentelemetry.io/otel/label"
 	"sort"
-	"time"
 )
 
 type Service struct {
-	orderCli shopware.OrderService
-	engine   checks.Engine
+	source      sources.Source
+	engine      checks.Engine
+	parallelism int
+	recorder    Recorder
+	watched     map[string]bool
 }
 
-func NewService(orderCli shopware.OrderService, engine checks.Engine) Service {
-	return Service{
-		orderCli: orderCli,
-		engine:   engine,
-	}
+// Recorder receives every distinct scanned order, i.e. to write a snapshot.
+type Recorder interface {
+	Record(order domain.Order) error
 }
 
-type FilterRequest struct {
-	From, To                  time.Time
-	IncludeCreated            bool
-	IncludeUpdated            bool
-	IncludeDeliveryUpdated    bool
-	IncludeTransactionUpdated bool
-}
-
-// returns only bad orders
-func (s Service) ScanOrders(ctx context.Context, req FilterRequest) ([]domain.OrderResult, int, error) {
-	var result []domain.OrderResult
-	processed := map[string]bool{}
-
-	if req.IncludeUpdated {
-		orders, err := s.getAllOrders(ctx, s.orderCli.SearchByTimeRange, "updatedAt", req.From, req.To)
-		if err != nil {
-			return nil, 0, fmt.Errorf("SearchByTimeRange(updatedAt) : %w", err)
-		}
-		badOrders := s.processOrders(orders, processed)
-		result = append(result, badOrders...)
+// NewService creates a service which checks at most parallelism orders at once.
+func NewService(source sources.Source, engine checks.Engine, parallelism int) Service {
+	if parallelism < 1 {
+		parallelism = 1
 	}
 
-	if req.IncludeCreated {
-		orders, err := s.getAllOrders(ctx, s.orderCli.SearchByTimeRange, "createdAt", req.From, req.To)
-		if err != nil {
-			return nil, 0, fmt.Errorf("SearchByTimeRange(createdAt) : %w", err)
-		}
-		badOrders := s.processOrders(orders, processed)
-		result = append(result, badOrders...)
+	return Service{
+		source:      source,
+		engine:      engine,
+		parallelism: parallelism,
 	}
+}
 
-	if req.IncludeDeliveryUpdated {
-		orders, err := s.getAllOrders(ctx, s.searchOrdersByDeliveries, "updatedAt", req.From, req.To)
-		if err != nil {
-			return nil, 0, fmt.Errorf("searchOrdersByDeliveries : %w", err)
-		}
-		badOrders := s.processOrders(orders, processed)
-		result = append(result, badOrders...)
-	}
+// Fields lists the order fields read to report bad orders.
+var Fields = []domain.Field{domain.FieldDeliveryTrackingCodes}
 
-	if req.IncludeTransactionUpdated {
-		orders, err := s.getAllOrders(ctx, s.searchOrdersByTransactions, "updatedAt", req.From, req.To)
-		if err != nil {
-			return nil, 0, fmt.Errorf("searchOrdersByTransactions : %w", err)
-		}
-		badOrders := s.processOrders(orders, processed)
-		result = append(result, badOrders...)
-	}
+// WithRecorder returns a copy of the service passing scanned orders to the recorder.
+func (s Service) WithRecorder(r Recorder) Service {
+	s.recorder = r
+	return s
+}
 
-	sortResult(result)
-	return result, len(processed), nil
+// WithWatched returns a copy of the service reporting which of the orders by ids it checks, i.e. orders of open findings.
+func (s Service) WithWatched(ids map[string]bool) Service {
+	s.watched = ids
+	return s
 }
 
-func (s Service) getAllOrders(ctx context.Context,
-	searchFunc func(context.Context, string, time.Time, time.Time, int) ([]shopware.Order, error),
-	field string, from, to time.Time) ([]shopware.Order, error) {
+type ScanResult struct {
+	Orders    []domain.OrderResult // only bad orders
+	Rechecked []string             // ids of watched orders checked, good and bad ones
+	Scanned   int                  // distinct orders checked
+	Pages     int                  // pages fetched from the source
+	Rows      int                  // rows read by the source, including duplicates
+}
 
-	var result []shopware.Order
-	page := 1
-	for {
-		pageOrders, err := searchFunc(ctx, field, from, to, page)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get orders : %w", err)
-		}
-		result = append(result, pageOrders...)
-		page++
+// ScanOrders streams orders of the source through the checks engine and returns only bad ones.
+func (s Service) ScanOrders(ctx context.Context, req sources.FilterRequest) (_ ScanResult, err error) {
+	ctx, span := tracing.Start(ctx, "ScanOrders", spanLabels(req)...)
+	defer func() { tracing.End(ctx, span, err) }()
 
-		if len(pageOrders) < shopware.MaxSearchLimit {
-			break
+	var badOrders []domain.OrderResult
+	var rechecked []string
+	result, err := newPipeline(s).run(ctx, req, func(e Evaluation) {
+		if s.watched[e.Order.ID] {
+			rechecked = append(rechecked, e.Order.ID)
 		}
+		if order, bad := toOrderResult(e); bad {
+			badOrders = append(badOrders, order)
+		}
+	})
+	if err != nil {
+		return ScanResult{}, err
 	}
-	zap.S().Infof("got %d orders by %s", len(result), field)
+
+	sortResult(badOrders)
+	result.Orders = badOrders
+	result.Rechecked = rechecked
+	span.SetAttributes(label.Int("scanned", result.Scanned), label.Int("detected", len(badOrders)))
 	return result, nil
 }
 
-func (s Service) searchOrdersByDeliveries(ctx context.Context, _ string, from, to time.Time, page int) ([]shopware.Order, error) {
-	deliveries, err := s.orderCli.SearchDeliveriesByTimeRange(ctx, "updatedAt", from, to, page)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get deliveries by updatedAt : %w", err)
-	}
+// EvaluateOrders returns outcomes of all checks, including passed and skipped ones, for every order, sorted by number.
+// It is meant for a few orders looked up by ids or numbers.
+func (s Service) EvaluateOrders(ctx context.Context, req sources.FilterRequest) (_ []Evaluation, err error) {
+	ctx, span := tracing.Start(ctx, "EvaluateOrders", spanLabels(req)...)
+	defer func() { tracing.End(ctx, span, err) }()
 
-	if len(deliveries) == 0 {
-		return []shopware.Order{}, nil
+	var evaluations []Evaluation
+	_, err = newPipeline(s).run(ctx, req, func(e Evaluation) {
+		evaluations = append(evaluations, e)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	var orderIDs []string
-	for _, d := range deliveries {
-		orderIDs = append(orderIDs, d.OrderID)
-	}
-	return s.orderCli.SearchByIDs(ctx, orderIDs)
+	sort.Slice(evaluations, func(i, j int) bool {
+		return evaluations[i].Order.Number < evaluations[j].Order.Number
+	})
+	return evaluations, nil
 }
 
-func (s Service) searchOrdersByTransactions(ctx context.Context, _ string, from, to time.Time, page int) ([]shopware.Order, error) {
-	txs, err := s.orderCli.SearchTransactionsByTimeRange(ctx, "updatedAt", from, to, page)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get transactions by updatedAt : %w", err)
+// EvaluationsResult returns the bad orders of evaluations like ScanOrders does, all of them count as rechecked.
+func EvaluationsResult(evaluations []Evaluation) ScanResult {
+	result := ScanResult{Scanned: len(evaluations)}
+	for _, e := range evaluations {
+		result.Rechecked = append(result.Rechecked, e.Order.ID)
+		if order, bad := toOrderResult(e); bad {
+			result.Orders = append(result.Orders, order)
+		}
 	}
+	sortResult(result.Orders)
+	return result
+}
 
-	if len(txs) == 0 {
-		return []shopware.Order{}, nil
+func spanLabels(req sources.FilterRequest) []label.KeyValue {
+	if req.IsLookup() {
+		return []label.KeyValue{label.Int("orderIds", len(req.OrderIDs)), label.Int("orderNumbers", len(req.OrderNumbers))}
 	}
-
-	var orderIDs []string
-	for _, d := range txs {
-		orderIDs = append(orderIDs, d.OrderID)
+	if req.IsSweep() {
+		return []label.KeyValue{label.String("states", fmt.Sprint(req.States))}
 	}
-	return s.orderCli.SearchByIDs(ctx, orderIDs)
+	return []label.KeyValue{label.String("from", req.From.String()), label.String("to", req.To.String())}
 }
 
-// returns only bad orders
-func (s Service) processOrders(orders []shopware.Order, processed map[string]bool) []domain.OrderResult {
-	var badOrders []domain.OrderResult
-	for _, order := range orders {
-		if processed[order.ID] {
-			continue
-		}
-		processed[order.ID] = true
-
-		errors := s.engine.ProcessOrder(order)
-		if len(errors) > 0 {
-			badOrders = append(badOrders, domain.OrderResult{
-				OrderID:      order.ID,
-				OrderNumber:  order.Number,
-				ChannelID:    order.SalesChannelID,
-				TrackingCode: domain.TrackingCode(order),
-				CreatedDate:  trySubString(order.CreatedAt, 10),
-				Errors:       errors,
-			})
+// returns false for good orders
+func toOrderResult(e Evaluation) (domain.OrderResult, bool) {
+	errors := map[string]error{}
+	for _, outcome := range e.Outcomes {
+		if outcome.Status == checks.StatusFailed {
+			errors[outcome.Rule] = outcome.Err
 		}
 	}
-	return badOrders
+	if len(errors) == 0 {
+		return domain.OrderResult{}, false
+	}
+
+	order := e.Order
+	return domain.OrderResult{
+		OrderID:      order.ID,
+		OrderNumber:  order.Number,
+		ChannelID:    order.SalesChannelID,
+		TrackingCode: domain.TrackingCode(order),
+		CreatedDate:  order.CreatedAt.Format("2006-01-02"),
+		Errors:       errors,
+	}, true
 }
 
 func sortResult(r []domain.OrderResult) {
@@ -178,10 +174,3 @@ func sortResult(r []domain.OrderResult) {
 		return r[i].OrderNumber < r[j].OrderNumber
 	})
 }
-
-func trySubString(s string, l int) string {
-	if len(s) <= l {
-		return s
-	}
-	return s[:l]
-}
@@ -0,0 +1,218 @@
+package orders_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/checks"
+	"github.com/nikolayk812/shopware-orders-scanner/checks/common"
+	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
+	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware/shopwaretest"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	"github.com/nikolayk812/shopware-orders-scanner/orders"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	swsource "github.com/nikolayk812/shopware-orders-scanner/sources/shopware"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	from = time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	to   = from.AddDate(0, 0, 1)
+)
+
+// fixtures are good order 10001 and bad ones 10002 to 10004, only 10004 was neither created nor updated within the window.
+func fixtures() []shopware.Order {
+	before, within := from.Add(-time.Hour), from.Add(time.Hour)
+	shipped := func(at time.Time, codes ...string) *shopwaretest.DeliveryBuilder {
+		return shopwaretest.NewDelivery(shopware.OrderDeliveryStateShipped).UpdatedAt(at).TrackingCodes(codes...)
+	}
+
+	return []shopware.Order{
+		shopwaretest.NewOrder(shopwaretest.ID(1)).Number("10001").CreatedAt(within).UpdatedAt(within).
+			Delivery(shipped(within, "code")).Document("pdf", "delivery_note.pdf").Build(),
+		shopwaretest.NewOrder(shopwaretest.ID(2)).Number("10002").CreatedAt(within).UpdatedAt(within).
+			Delivery(shipped(within)).Document("pdf", "delivery_note.pdf").Build(),
+		shopwaretest.NewOrder(shopwaretest.ID(3)).Number("10003").CreatedAt(before).UpdatedAt(before).
+			Delivery(shipped(within, "code")).Document("xml", "invoice.xml").Build(),
+		shopwaretest.NewOrder(shopwaretest.ID(4)).Number("10004").State(shopware.OrderStateInProgress).CreatedAt(before).UpdatedAt(before).
+			Delivery(shipped(before)).Build(),
+	}
+}
+
+func newService() orders.Service {
+	source := swsource.NewSource(shopwaretest.NewOrderService(fixtures()...), 2)
+	engine := checks.NewEngine(map[string]checks.Check{
+		"TRACKING_CODE": common.ShippedTrackingCode{},
+		"PDF_DOCUMENT":  common.ShippedPdfDocument{},
+	})
+	return orders.NewService(source, engine, 2)
+}
+
+type recorder struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (r *recorder) Record(order domain.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ids = append(r.ids, order.ID)
+	return nil
+}
+
+// failures returns failed rules by order number.
+func failures(result orders.ScanResult) map[string][]string {
+	m := map[string][]string{}
+	for _, o := range result.Orders {
+		for rule := range o.Errors {
+			m[o.OrderNumber] = append(m[o.OrderNumber], rule)
+		}
+		sort.Strings(m[o.OrderNumber])
+	}
+	return m
+}
+
+func TestService_ScanOrders(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         sources.FilterRequest
+		wantScanned int
+		wantRows    int
+		want        map[string][]string
+	}{
+		{"created", sources.FilterRequest{From: from, To: to, IncludeCreated: true}, 2, 2,
+			map[string][]string{"10002": {"TRACKING_CODE"}}},
+		{"deliveries updated", sources.FilterRequest{From: from, To: to, IncludeDeliveryUpdated: true}, 3, 3,
+			map[string][]string{"10002": {"TRACKING_CODE"}, "10003": {"PDF_DOCUMENT"}}},
+		{"all searches, orders found by several of them checked once", sources.FilterRequest{From: from, To: to,
+			IncludeCreated: true, IncludeUpdated: true, IncludeDeliveryUpdated: true, IncludeTransactionUpdated: true}, 3, 7,
+			map[string][]string{"10002": {"TRACKING_CODE"}, "10003": {"PDF_DOCUMENT"}}},
+		{"sweep", sources.FilterRequest{States: []domain.OrderState{domain.OrderStateInProgress}}, 1, 1,
+			map[string][]string{"10004": {"PDF_DOCUMENT", "TRACKING_CODE"}}},
+		{"lookup", sources.FilterRequest{OrderNumbers: []string{"10001", "10004"}}, 2, 2,
+			map[string][]string{"10004": {"PDF_DOCUMENT", "TRACKING_CODE"}}},
+		{"nothing", sources.FilterRequest{From: to.Add(time.Hour), To: to.Add(2 * time.Hour), IncludeCreated: true}, 0, 0,
+			map[string][]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			result, err := newService().WithRecorder(rec).ScanOrders(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("ScanOrders: %v", err)
+			}
+
+			if result.Scanned != tt.wantScanned || result.Rows != tt.wantRows {
+				t.Errorf("got %d scanned of %d rows, want %d of %d", result.Scanned, result.Rows, tt.wantScanned, tt.wantRows)
+			}
+			if got := failures(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got failures %v, want %v", got, tt.want)
+			}
+			if len(rec.ids) != tt.wantScanned {
+				t.Errorf("got %d recorded orders, want %d", len(rec.ids), tt.wantScanned)
+			}
+		})
+	}
+}
+
+// TestService_ScanOrdersWatched reports only the watched orders among the checked ones, good and bad.
+func TestService_ScanOrdersWatched(t *testing.T) {
+	watched := map[string]bool{shopwaretest.ID(1): true, shopwaretest.ID(2): true, shopwaretest.ID(4): true}
+	req := sources.FilterRequest{From: from, To: to, IncludeCreated: true, IncludeDeliveryUpdated: true}
+
+	result, err := newService().WithWatched(watched).ScanOrders(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ScanOrders: %v", err)
+	}
+
+	sort.Strings(result.Rechecked)
+	if want := []string{shopwaretest.ID(1), shopwaretest.ID(2)}; !reflect.DeepEqual(result.Rechecked, want) {
+		t.Errorf("got rechecked %v, want %v", result.Rechecked, want)
+	}
+}
+
+func TestService_EvaluateOrders(t *testing.T) {
+	evaluations, err := newService().EvaluateOrders(context.Background(), sources.FilterRequest{OrderNumbers: []string{"10003", "10001", "99999"}})
+	if err != nil {
+		t.Fatalf("EvaluateOrders: %v", err)
+	}
+
+	var got []string
+	for _, e := range evaluations {
+		for _, o := range e.Outcomes {
+			got = append(got, e.Order.Number+" "+o.Rule+" "+string(o.Status))
+		}
+	}
+	want := []string{
+		"10001 PDF_DOCUMENT PASSED",
+		"10001 TRACKING_CODE PASSED",
+		"10003 PDF_DOCUMENT FAILED",
+		"10003 TRACKING_CODE PASSED",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got outcomes %v, want %v", got, want)
+	}
+
+	result := orders.EvaluationsResult(evaluations)
+	if result.Scanned != 2 || !reflect.DeepEqual(failures(result), map[string][]string{"10003": {"PDF_DOCUMENT"}}) {
+		t.Errorf("got result %+v, want 2 scanned and 10003 failing PDF_DOCUMENT", result)
+	}
+}
+
+// updatingService puts update into the orders once the first page of a search is returned,
+// like a Shopware admin editing an order while it is scanned.
+type updatingService struct {
+	*shopwaretest.OrderService
+	once   sync.Once
+	update []shopware.Order
+}
+
+func (s *updatingService) SearchByTimeRange(ctx context.Context, field string, gte, lte time.Time, afterID string) ([]shopware.Order, error) {
+	orders, err := s.OrderService.SearchByTimeRange(ctx, field, gte, lte, afterID)
+	s.once.Do(func() { s.Put(s.update...) })
+	return orders, err
+}
+
+func TestService_ScanOrdersUpdatedBetweenPages(t *testing.T) {
+	const count = shopware.MaxSearchLimit + 1
+	order := func(i int, updatedAt time.Time) *shopwaretest.OrderBuilder {
+		return shopwaretest.NewOrder(shopwaretest.ID(i)).Number(fmt.Sprint(10000 + i)).UpdatedAt(updatedAt)
+	}
+
+	var fixtures []shopware.Order
+	for i := 1; i <= count; i++ {
+		fixtures = append(fixtures, order(i, from.Add(time.Minute)).Build())
+	}
+	service := &updatingService{
+		OrderService: shopwaretest.NewOrderService(fixtures...),
+		// an order of the first page and one of the second page move to the end of the window
+		update: []shopware.Order{
+			order(1, from.Add(2*time.Hour)).Build(),
+			order(count, from.Add(2*time.Hour)).Delivery(shopwaretest.NewDelivery(shopware.OrderDeliveryStateShipped)).Build(),
+		},
+	}
+	engine := checks.NewEngine(map[string]checks.Check{"TRACKING_CODE": common.ShippedTrackingCode{}})
+
+	rec := &recorder{}
+	result, err := orders.NewService(swsource.NewSource(service, 1), engine, 1).WithRecorder(rec).
+		ScanOrders(context.Background(), sources.FilterRequest{From: from, To: to, IncludeUpdated: true})
+	if err != nil {
+		t.Fatalf("ScanOrders: %v", err)
+	}
+
+	seen := map[string]bool{}
+	for _, id := range rec.ids {
+		seen[id] = true
+	}
+	if result.Scanned != count || len(seen) != count {
+		t.Errorf("got %d scanned and %d distinct recorded orders, want %d", result.Scanned, len(seen), count)
+	}
+	// the order updated before its page was fetched is checked in its updated state
+	want := map[string][]string{fmt.Sprint(10000 + count): {"TRACKING_CODE"}}
+	if got := failures(result); !reflect.DeepEqual(got, want) {
+		t.Errorf("got failures %v, want %v", got, want)
+	}
+}
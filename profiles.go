@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/config"
+	"github.com/nikolayk812/shopware-orders-scanner/consumers/html"
+	ms "github.com/nikolayk812/shopware-orders-scanner/mail"
+	"github.com/nikolayk812/shopware-orders-scanner/orders"
+	"github.com/nikolayk812/shopware-orders-scanner/tracing"
+	"go.opentelemetry.io/otel/label"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"html/template"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+const (
+	consumerMail = "mail"
+	consumerHTML = "html"
+)
+
+// profileFlags select shop profiles of a config file, without one the only shop is configured by environment variables.
+type profileFlags struct {
+	configPath string
+	name       string
+}
+
+func addProfileFlags(flags *flag.FlagSet) *profileFlags {
+	var f profileFlags
+	flags.StringVar(&f.configPath, "config", "", "YAML file with shop profiles, the shop is configured by environment variables if not set")
+	flags.StringVar(&f.name, "profile", "", "run only the profile with this name, all profiles of the config file if not set")
+	return &f
+}
+
+// load returns the selected profiles sorted by name and the combined report of the config file.
+func (f profileFlags) load() (config.Shops, error) {
+	if f.configPath == "" {
+		if f.name != "" {
+			return config.Shops{}, configError(errors.New("-profile requires -config"))
+		}
+
+		var p config.Profile
+		if err := config.Parse("local.env", &p.Shopware); err != nil {
+			return config.Shops{}, configError(fmt.Errorf("config.Parse: %w", err))
+		}
+		if err := config.Parse("local.env", &p.SendGrid); err != nil {
+			return config.Shops{}, configError(fmt.Errorf("config.Parse: %w", err))
+		}
+		if err := validateProfile(p); err != nil {
+			return config.Shops{}, configError(err)
+		}
+		return config.Shops{Profiles: []config.Profile{p}}, nil
+	}
+
+	shops, err := config.LoadShops(f.configPath)
+	if err != nil {
+		return config.Shops{}, configError(fmt.Errorf("config.LoadShops : %w", err))
+	}
+	for _, p := range shops.Profiles {
+		if err := validateProfile(p); err != nil {
+			return config.Shops{}, configError(fmt.Errorf("profile [%s] : %w", p.Name, err))
+		}
+	}
+	if err := multierr.Append(shops.Report.SendGrid.Validate(), validateConsumers(shops.Report.Consumers, shops.Report.SendGrid)); err != nil {
+		return config.Shops{}, configError(fmt.Errorf("report : %w", err))
+	}
+
+	if f.name == "" {
+		return shops, nil
+	}
+	for _, p := range shops.Profiles {
+		if p.Name == f.name {
+			shops.Profiles = []config.Profile{p}
+			return shops, nil
+		}
+	}
+	return config.Shops{}, configError(fmt.Errorf("profile [%s] not found in [%s]", f.name, f.configPath))
+}
+
+// single returns the only selected profile, for commands working on a single shop.
+func (f profileFlags) single() (config.Profile, error) {
+	shops, err := f.load()
+	if err != nil {
+		return config.Profile{}, err
+	}
+	if len(shops.Profiles) > 1 {
+		return config.Profile{}, configError(fmt.Errorf("select one of %d profiles of [%s] by -profile", len(shops.Profiles), f.configPath))
+	}
+	return shops.Profiles[0], nil
+}
+
+// validateProfile reports all problems of the profile at once, without accessing the shop.
+func validateProfile(p config.Profile) error {
+	err := multierr.Combine(p.Shopware.Validate(), p.SendGrid.Validate(), validateConsumers(p.Consumers, p.SendGrid))
+	for _, name := range p.Checks {
+		if _, ok := rules[name]; !ok {
+			err = multierr.Append(err, fmt.Errorf("unknown check [%s]", name))
+		}
+	}
+	return err
+}
+
+func validateConsumers(consumers []string, sgConf config.SendGrid) error {
+	var err error
+	for _, c := range consumers {
+		switch c {
+		case consumerMail:
+			if !sgConf.Enabled {
+				err = multierr.Append(err, fmt.Errorf("consumer [%s] requires sendgrid to be enabled", c))
+			}
+		case consumerHTML:
+		default:
+			err = multierr.Append(err, fmt.Errorf("unknown consumer [%s]", c))
+		}
+	}
+	return err
+}
+
+// validateTemplates parses report and dashboard templates, which are otherwise read only once a scan has finished.
+func validateTemplates() error {
+	var err error
+	for _, path := range []string{reportTemplate, shopsTemplate, dashboardTemplate} {
+		if _, parseErr := template.ParseFiles(path); parseErr != nil {
+			err = multierr.Append(err, fmt.Errorf("template.ParseFiles [%s] : %w", path, parseErr))
+		}
+	}
+	if err != nil {
+		return configError(err)
+	}
+	return nil
+}
+
+// shopRun is the outcome of a scan or sweep of a single shop.
+type shopRun struct {
+	profile config.Profile
+	result  orders.ScanResult
+	err     error
+}
+
+// runShops runs every profile concurrently, each with its own Shopware clients, a failing shop doesn't abort the rest.
+// Several shops are also reported together, its failure is returned.
+func runShops(ctx context.Context, cfg mainConfig, shops config.Shops, snapshotPath string,
+	run func(context.Context, mainConfig, string) (orders.ScanResult, error)) ([]shopRun, error) {
+	runs := make([]shopRun, len(shops.Profiles))
+	var wg sync.WaitGroup
+	for i, p := range shops.Profiles {
+		wg.Add(1)
+		go func(i int, p config.Profile) {
+			defer wg.Done()
+			shopCfg := cfg
+			shopCfg.Profile = p
+			result, err := run(ctx, shopCfg, snapshotPath)
+			if err != nil && p.Name != "" {
+				err = fmt.Errorf("profile [%s] : %w", p.Name, err)
+			}
+			runs[i] = shopRun{profile: p, result: result, err: err}
+		}(i, p)
+	}
+	wg.Wait()
+
+	if len(runs) < 2 {
+		return runs, nil
+	}
+	if err := reportShops(ctx, shops.Report, runs); err != nil {
+		return runs, consumerError(fmt.Errorf("reportShops : %w", err))
+	}
+	return runs, nil
+}
+
+// reportShops sends a report of all shops grouped per shop to the consumers of the combined report.
+func reportShops(ctx context.Context, report config.Report, runs []shopRun) (err error) {
+	ctx, span := tracing.Start(ctx, "reportShops", label.Int("shops", len(runs)))
+	defer func() { tracing.End(ctx, span, err) }()
+
+	var shops []html.Shop
+	for _, r := range runs {
+		shops = append(shops, html.Shop{
+			Name:     r.profile.Name,
+			BaseURL:  r.profile.Shopware.BaseURL,
+			Channels: r.profile.SalesChannels,
+			Orders:   r.result.Orders,
+			Scanned:  r.result.Scanned,
+			Err:      r.err,
+		})
+	}
+	document, err := html.NewShopsRenderer(shopsTemplate).Consume(shops)
+	if err != nil {
+		return fmt.Errorf("shopsRenderer.Consume : %w", err)
+	}
+
+	consumers := report.Consumers
+	if len(consumers) == 0 {
+		consumers = []string{consumerHTML}
+	}
+	for _, c := range consumers {
+		switch c {
+		case consumerMail:
+			if err := ms.NewSender(report.SendGrid).SendMail(string(document.Bytes)); err != nil {
+				return fmt.Errorf("sender.SendMail : %w", err)
+			}
+		case consumerHTML:
+			fileName := "./reports/report_shops_" + time.Now().Format("01-02-2006_15:04") + ".html"
+			if err := ioutil.WriteFile(fileName, document.Bytes, 0644); err != nil {
+				return fmt.Errorf("WriteFile : %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkShops fails like checkFindings for every shop which hasn't failed otherwise, with the highest exit code.
+func checkShops(cfg config.Scan, runs []shopRun, reportErr error) error {
+	errs := []error{reportErr}
+	for _, r := range runs {
+		err := r.err
+		if err == nil {
+			err = checkFindings(cfg, r.result)
+			if err != nil && r.profile.Name != "" {
+				err = fmt.Errorf("profile [%s] : %w", r.profile.Name, err)
+			}
+		}
+		errs = append(errs, err)
+	}
+	return worstError(errs)
+}
+
+// worstError returns the error of the highest exit code, errors of several shops are logged.
+func worstError(errs []error) error {
+	var worst error
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if failed > 1 {
+			log := zap.L().Error
+			if exitCode(err) == exitFindings {
+				log = zap.L().Warn
+			}
+			log("shop has not passed", zap.Error(err))
+		}
+		if exitCode(err) > exitCode(worst) {
+			worst = err
+		}
+	}
+	return worst
+}
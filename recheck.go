@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/config"
+	"github.com/nikolayk812/shopware-orders-scanner/orders"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	"github.com/nikolayk812/shopware-orders-scanner/sources/file"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+type recheckConfig struct {
+	// only used for links in the report, Shopware is not accessed
+	ShopwareBaseURL string `envconfig:"SHOPWARE_BASE_URL"`
+	config.SendGrid
+	config.Scan
+}
+
+// runRecheck runs the checks against a snapshot written by the scan command or another supported order export.
+// The report is written into the reports directory, -mail delivers it to the consumers of the profile like a scan does.
+func runRecheck(args []string) error {
+	flags := flag.NewFlagSet("recheck", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: recheck [-mail] [-config shops.yaml -profile name] <orders.ndjson|orders.json|orders.csv>")
+		flags.PrintDefaults()
+	}
+	sendMail := flags.Bool("mail", false, "send the report to the consumers of the profile, by default it's only written into the reports directory")
+	pf := addProfileFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return configError(err)
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return configError(fmt.Errorf("expected a single orders file"))
+	}
+	path := flags.Arg(0)
+
+	var cfg recheckConfig
+	if err := config.Parse("local.env", &cfg); err != nil {
+		return configError(fmt.Errorf("config.Parse: %w", err))
+	}
+	if err := multierr.Combine(validateExit(cfg.Scan), validateTemplates()); err != nil {
+		return err
+	}
+	profile := config.Profile{Shopware: config.Shopware{BaseURL: cfg.ShopwareBaseURL}, SendGrid: cfg.SendGrid}
+	if pf.configPath != "" || pf.name != "" {
+		p, err := pf.single()
+		if err != nil {
+			return err
+		}
+		profile = p
+	}
+	if !*sendMail {
+		// old data must not reach the recipients unless asked for
+		profile.Consumers = []string{consumerHTML}
+	} else if err := profile.SendGrid.Validate(); err != nil {
+		return configError(err)
+	}
+
+	service := orders.NewService(file.NewSource(path), buildEngine(profile.Checks), cfg.Scan.Parallelism)
+	// zero time range selects all orders of the file
+	result, err := service.ScanOrders(context.Background(), sources.FilterRequest{})
+	if err != nil {
+		return sourceError(fmt.Errorf("failed to recheck orders of [%s] : %w", path, err))
+	}
+	zap.L().Info("detected suspicious orders", zap.String("run", "recheck"), zap.String("path", path),
+		zap.Int("suspicious", len(result.Orders)), zap.Int("scanned", result.Scanned))
+
+	if err := consume(context.Background(), profile, result); err != nil {
+		return consumerError(err)
+	}
+	return checkFindings(cfg.Scan, result)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/api"
+	"github.com/nikolayk812/shopware-orders-scanner/config"
+	"github.com/nikolayk812/shopware-orders-scanner/dashboard"
+	"github.com/nikolayk812/shopware-orders-scanner/findings"
+	"github.com/nikolayk812/shopware-orders-scanner/metrics"
+	"github.com/nikolayk812/shopware-orders-scanner/orders"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout bounds waiting for HTTP requests in progress on shutdown.
+const shutdownTimeout = 10 * time.Second
+
+// validateServe parses schedules and their time zone.
+func validateServe(cfg config.Serve) error {
+	var err error
+	schedules := []struct{ name, spec string }{
+		{"SERVE_SCAN_SCHEDULE", cfg.ScanSchedule},
+		{"SERVE_SWEEP_SCHEDULE", cfg.SweepSchedule},
+	}
+	for _, s := range schedules {
+		if s.spec == "" {
+			continue
+		}
+		if _, parseErr := cron.ParseStandard(s.spec); parseErr != nil {
+			err = multierr.Append(err, fmt.Errorf("%s [%s] : %w", s.name, s.spec, parseErr))
+		}
+	}
+	if _, locErr := time.LoadLocation(cfg.TimeZone); locErr != nil {
+		err = multierr.Append(err, fmt.Errorf("SERVE_TIMEZONE [%s] : %w", cfg.TimeZone, locErr))
+	}
+	return err
+}
+
+// runServe runs scans and sweeps on their schedules and serves the API, the dashboard and metrics if enabled until SIGTERM or SIGINT,
+// which cancels the run in progress and waits for it to return.
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	pf := addProfileFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return configError(err)
+	}
+
+	var cfg mainConfig
+	if err := config.Parse("local.env", &cfg); err != nil {
+		return configError(fmt.Errorf("config.Parse: %w", err))
+	}
+	var serveCfg config.Serve
+	if err := config.Parse("local.env", &serveCfg); err != nil {
+		return configError(fmt.Errorf("config.Parse: %w", err))
+	}
+	var apiCfg config.API
+	if err := config.Parse("local.env", &apiCfg); err != nil {
+		return configError(fmt.Errorf("config.Parse: %w", err))
+	}
+	var dashboardCfg config.Dashboard
+	if err := config.Parse("local.env", &dashboardCfg); err != nil {
+		return configError(fmt.Errorf("config.Parse: %w", err))
+	}
+	var metricsCfg config.Metrics
+	if err := config.Parse("local.env", &metricsCfg); err != nil {
+		return configError(fmt.Errorf("config.Parse: %w", err))
+	}
+	if apiCfg.Addr != "" && apiCfg.Token == "" {
+		return configError(errors.New("API_TOKEN is required to serve the API"))
+	}
+	if err := multierr.Combine(validateServe(serveCfg), metricsCfg.Validate(), validateTemplates()); err != nil {
+		return configError(err)
+	}
+	shops, err := pf.load()
+	if err != nil {
+		return err
+	}
+	if apiCfg.Addr != "" && len(shops.Profiles) > 1 {
+		return configError(errors.New("the API serves a single shop, select it by -profile"))
+	}
+
+	loc, err := time.LoadLocation(serveCfg.TimeZone)
+	if err != nil {
+		return configError(fmt.Errorf("time.LoadLocation [%s] : %w", serveCfg.TimeZone, err))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// a single slot shared by all jobs, so a slow sweep also holds off the next scan
+	running := make(chan struct{}, 1)
+	job := func(name string, run func(context.Context, mainConfig, string) (orders.ScanResult, error)) func() {
+		return func() {
+			select {
+			case running <- struct{}{}:
+				defer func() { <-running }()
+			default:
+				zap.L().Warn("skipping scheduled run, the previous one is still in progress", zap.String("run", name))
+				return
+			}
+
+			log := zap.L().With(zap.String("run", name))
+			start := time.Now()
+			log.Info("starting scheduled run")
+			runs, err := runShops(ctx, cfg, shops, "", run)
+			errs := []error{err}
+			for _, r := range runs {
+				errs = append(errs, r.err)
+			}
+			if err := worstError(errs); err != nil {
+				log.Error("scheduled run has failed", zap.Duration("duration", time.Since(start)), zap.Error(err))
+				return
+			}
+			log.Info("scheduled run has succeeded", zap.Duration("duration", time.Since(start)))
+		}
+	}
+
+	c := cron.New(cron.WithLocation(loc))
+	schedules := []struct {
+		name string
+		spec string
+		run  func(context.Context, mainConfig, string) (orders.ScanResult, error)
+	}{
+		{"scan", serveCfg.ScanSchedule, scan},
+		{"sweep", serveCfg.SweepSchedule, sweep},
+	}
+	for _, s := range schedules {
+		if s.spec == "" {
+			continue
+		}
+		if _, err := c.AddFunc(s.spec, job(s.name, s.run)); err != nil {
+			return configError(fmt.Errorf("invalid %s schedule [%s] : %w", s.name, s.spec, err))
+		}
+		zap.S().Infow("scheduled run", "run", s.name, "spec", s.spec, "location", loc.String())
+	}
+
+	var servers []*http.Server
+	serverErrs := make(chan error, 1)
+	listen := func(name, addr string, handler http.Handler) {
+		srv := &http.Server{Addr: addr, Handler: handler}
+		servers = append(servers, srv)
+		go func() {
+			if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+				serverErrs <- fmt.Errorf("%s : %w", name, err)
+			}
+		}()
+		zap.S().Infow("serving", "server", name, "addr", addr)
+	}
+
+	if apiCfg.Addr != "" {
+		shopCfg := cfg
+		shopCfg.Profile = shops.Profiles[0]
+		service, closeService, err := buildService(shopCfg, "")
+		if err != nil {
+			return fmt.Errorf("buildService : %w", err)
+		}
+		defer closeService()
+
+		listen("API", apiCfg.Addr, api.NewHandler(ctx, service, string(apiCfg.Token)))
+	}
+	if dashboardCfg.Addr != "" {
+		// channel ids are unique across shops, so the names of all profiles fit into one map
+		channels := map[string]string{}
+		for _, p := range shops.Profiles {
+			for id, name := range p.SalesChannels {
+				channels[id] = name
+			}
+		}
+		store := findings.NewStore(cfg.Findings.Path)
+		listen("dashboard", dashboardCfg.Addr, dashboard.NewHandler(store, dashboardTemplate, channels))
+	}
+
+	if metricsCfg.Addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		listen("metrics", metricsCfg.Addr, mux)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+
+	c.Start()
+	var serveErr error
+	select {
+	case sig := <-signals:
+		zap.S().Infow("waiting for the run in progress to stop", "signal", sig.String())
+	case err := <-serverErrs:
+		serveErr = fmt.Errorf("ListenAndServe : %w", err)
+	}
+
+	cancel()
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			zap.L().Error("failed to shut down server", zap.String("addr", srv.Addr), zap.Error(err))
+		}
+	}
+	<-c.Stop().Done()
+	return serveErr
+}
@@ -0,0 +1,77 @@
+// Package snapshot stores scanned orders as newline delimited JSON, one domain.Order per line,
+// so checks can be re-run offline against exactly the same data.
+package snapshot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	"io"
+	"os"
+	"sync"
+)
+
+// Writer is safe for concurrent use.
+type Writer struct {
+	file *os.File
+
+	mu      sync.Mutex
+	buf     *bufio.Writer // guarded by mu
+	encoder *json.Encoder // guarded by mu
+}
+
+// NewWriter creates or truncates the snapshot file.
+func NewWriter(path string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Create [%s] : %w", path, err)
+	}
+
+	buf := bufio.NewWriter(f)
+	return &Writer{
+		file:    f,
+		buf:     buf,
+		encoder: json.NewEncoder(buf),
+	}, nil
+}
+
+func (w *Writer) Record(order domain.Order) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.encoder.Encode(order); err != nil {
+		return fmt.Errorf("encoder.Encode [%s] : %w", order.ID, err)
+	}
+	return nil
+}
+
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.buf.Flush(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("buf.Flush : %w", err)
+	}
+	return w.file.Close()
+}
+
+// Read decodes orders one by one and passes them to f until the end of the snapshot or the first error.
+func Read(r io.Reader, f func(domain.Order) error) error {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	for line := 1; ; line++ {
+		var order domain.Order
+		err := decoder.Decode(&order)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("decoder.Decode order #%d : %w", line, err)
+		}
+
+		if err := f(order); err != nil {
+			return err
+		}
+	}
+}
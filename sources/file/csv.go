@@ -0,0 +1,237 @@
+package file
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	"io"
+	"strings"
+	"time"
+)
+
+// CSV columns, the header row is required and may list them in any order or omit some of them.
+// Consecutive rows of the same order id are merged into one order, every row contributing at most
+// one delivery, transaction, document and line item. Multiple tracking codes are separated by "|".
+// Timestamps are RFC 3339 or "2006-01-02 15:04:05" in UTC.
+const (
+	colID                   = "id"
+	colNumber               = "number"
+	colSalesChannelID       = "sales_channel_id"
+	colState                = "state"
+	colCreatedAt            = "created_at"
+	colUpdatedAt            = "updated_at"
+	colDeliveryID           = "delivery_id"
+	colDeliveryState        = "delivery_state"
+	colTrackingCodes        = "tracking_codes"
+	colDeliveryUpdatedAt    = "delivery_updated_at"
+	colTransactionID        = "transaction_id"
+	colTransactionState     = "transaction_state"
+	colTransactionCreatedAt = "transaction_created_at"
+	colTransactionUpdatedAt = "transaction_updated_at"
+	colDocumentFileType     = "document_file_type"
+	colDocumentFileName     = "document_file_name"
+	colProductID            = "product_id"
+	colProductNumber        = "product_number"
+)
+
+func readCSV(r io.Reader, emit func(domain.Order) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("read header : %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns[colID]; !ok {
+		return fmt.Errorf("missing [%s] column", colID)
+	}
+
+	var current *domain.Order
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read line %d : %w", line, err)
+		}
+
+		row := csvRow{columns: columns, record: record}
+		id := row.get(colID)
+		if current != nil && current.ID != id {
+			if err := emit(*current); err != nil {
+				return err
+			}
+			current = nil
+		}
+		if current == nil {
+			order, err := row.order()
+			if err != nil {
+				return fmt.Errorf("line %d : %w", line, err)
+			}
+			current = &order
+		}
+		if err := row.mergeInto(current); err != nil {
+			return fmt.Errorf("line %d : %w", line, err)
+		}
+	}
+
+	if current != nil {
+		return emit(*current)
+	}
+	return nil
+}
+
+type csvRow struct {
+	columns map[string]int
+	record  []string
+}
+
+func (r csvRow) get(column string) string {
+	i, ok := r.columns[column]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+func (r csvRow) time(column string) (time.Time, error) {
+	v := r.get(column)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02 15:04:05", v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("column [%s] : %w", column, err)
+	}
+	return t, nil
+}
+
+func (r csvRow) order() (domain.Order, error) {
+	createdAt, err := r.time(colCreatedAt)
+	if err != nil {
+		return domain.Order{}, err
+	}
+	updatedAt, err := r.time(colUpdatedAt)
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	return domain.Order{
+		ID:             r.get(colID),
+		Number:         r.get(colNumber),
+		SalesChannelID: r.get(colSalesChannelID),
+		State:          domain.OrderState(r.get(colState)),
+		CreatedAt:      createdAt,
+		UpdatedAt:      updatedAt,
+	}, nil
+}
+
+// mergeInto adds the delivery, transaction, document and line item of the row unless they're empty or repeated.
+func (r csvRow) mergeInto(order *domain.Order) error {
+	if state := r.get(colDeliveryState); state != "" {
+		updatedAt, err := r.time(colDeliveryUpdatedAt)
+		if err != nil {
+			return err
+		}
+		var codes []string
+		if tc := r.get(colTrackingCodes); tc != "" {
+			codes = strings.Split(tc, "|")
+		}
+		d := domain.Delivery{
+			ID:            r.get(colDeliveryID),
+			State:         domain.DeliveryState(state),
+			TrackingCodes: codes,
+			UpdatedAt:     updatedAt,
+		}
+		if !hasDelivery(*order, d) {
+			order.Deliveries = append(order.Deliveries, d)
+		}
+	}
+
+	if state := r.get(colTransactionState); state != "" {
+		createdAt, err := r.time(colTransactionCreatedAt)
+		if err != nil {
+			return err
+		}
+		updatedAt, err := r.time(colTransactionUpdatedAt)
+		if err != nil {
+			return err
+		}
+		tx := domain.Transaction{
+			ID:        r.get(colTransactionID),
+			State:     domain.TransactionState(state),
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
+		}
+		if !hasTransaction(*order, tx) {
+			order.Transactions = append(order.Transactions, tx)
+		}
+	}
+
+	if fileType := r.get(colDocumentFileType); fileType != "" {
+		doc := domain.Document{FileType: fileType, FileName: r.get(colDocumentFileName)}
+		if !hasDocument(*order, doc) {
+			order.Documents = append(order.Documents, doc)
+		}
+	}
+
+	if productNumber := r.get(colProductNumber); productNumber != "" {
+		item := domain.LineItem{ProductID: r.get(colProductID), ProductNumber: productNumber}
+		if !hasLineItem(*order, item) {
+			order.LineItems = append(order.LineItems, item)
+		}
+	}
+
+	return nil
+}
+
+func hasDelivery(order domain.Order, d domain.Delivery) bool {
+	for _, existing := range order.Deliveries {
+		if d.ID != "" && existing.ID == d.ID {
+			return true
+		}
+		if d.ID == "" && existing.State == d.State &&
+			strings.Join(existing.TrackingCodes, "|") == strings.Join(d.TrackingCodes, "|") {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTransaction(order domain.Order, tx domain.Transaction) bool {
+	for _, existing := range order.Transactions {
+		if tx.ID != "" && existing.ID == tx.ID {
+			return true
+		}
+		if tx.ID == "" && existing.State == tx.State && existing.CreatedAt.Equal(tx.CreatedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasDocument(order domain.Order, doc domain.Document) bool {
+	for _, existing := range order.Documents {
+		if existing == doc {
+			return true
+		}
+	}
+	return false
+}
+
+func hasLineItem(order domain.Order, item domain.LineItem) bool {
+	for _, existing := range order.LineItems {
+		if existing == item {
+			return true
+		}
+	}
+	return false
+}
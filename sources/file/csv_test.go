@@ -0,0 +1,119 @@
+package file_test
+
+import (
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want []domain.Order
+	}{
+		{
+			name: "columns in any order, padded and omitted",
+			csv: " state , number,id,updated_at,created_at\n" +
+				"Open,10001,1,2020-10-02T00:00:00Z,2020-10-01 00:00:00\n",
+			want: []domain.Order{{
+				ID: "1", Number: "10001", State: domain.OrderStateOpen, CreatedAt: day, UpdatedAt: day.AddDate(0, 0, 1),
+			}},
+		},
+		{
+			name: "short rows leave trailing columns empty",
+			csv:  "id,number,sales_channel_id\n1,10001\n",
+			want: []domain.Order{{ID: "1", Number: "10001"}},
+		},
+		{
+			name: "consecutive rows are merged into one order",
+			csv: "id,number,delivery_id,delivery_state,tracking_codes,delivery_updated_at," +
+				"transaction_id,transaction_state,transaction_created_at,document_file_type,document_file_name,product_id,product_number\n" +
+				"1,10001,d1,Shipped,a|b,2020-10-01 00:00:00,t1,Paid,2020-10-01 00:00:00,invoice,invoice.pdf,p1,SW1\n" +
+				"1,10001,d1,Shipped,a|b,2020-10-01 00:00:00,t2,Open,2020-10-02 00:00:00,invoice,invoice.pdf,p2,SW2\n" +
+				"1,10001,d2,Open,,,t1,Paid,2020-10-01 00:00:00,delivery_note,note.pdf,p1,SW1\n",
+			want: []domain.Order{{
+				ID: "1", Number: "10001",
+				Deliveries: []domain.Delivery{
+					{ID: "d1", State: domain.DeliveryStateShipped, TrackingCodes: []string{"a", "b"}, UpdatedAt: day},
+					{ID: "d2", State: domain.DeliveryStateOpen},
+				},
+				Transactions: []domain.Transaction{
+					{ID: "t1", State: domain.TransactionStatePaid, CreatedAt: day},
+					{ID: "t2", State: domain.TransactionStateOpen, CreatedAt: day.AddDate(0, 0, 1)},
+				},
+				Documents: []domain.Document{
+					{FileType: "invoice", FileName: "invoice.pdf"},
+					{FileType: "delivery_note", FileName: "note.pdf"},
+				},
+				LineItems: []domain.LineItem{{ProductID: "p1", ProductNumber: "SW1"}, {ProductID: "p2", ProductNumber: "SW2"}},
+			}},
+		},
+		{
+			name: "items without ids are deduplicated by their content",
+			csv: "id,delivery_state,tracking_codes,transaction_state,transaction_created_at\n" +
+				"1,Shipped,a,Paid,2020-10-01 00:00:00\n" +
+				"1,Shipped,a,Paid,2020-10-01 00:00:00\n" +
+				"1,Shipped,b,Paid,2020-10-02 00:00:00\n",
+			want: []domain.Order{{
+				ID: "1",
+				Deliveries: []domain.Delivery{
+					{State: domain.DeliveryStateShipped, TrackingCodes: []string{"a"}},
+					{State: domain.DeliveryStateShipped, TrackingCodes: []string{"b"}},
+				},
+				Transactions: []domain.Transaction{
+					{State: domain.TransactionStatePaid, CreatedAt: day},
+					{State: domain.TransactionStatePaid, CreatedAt: day.AddDate(0, 0, 1)},
+				},
+			}},
+		},
+		{
+			name: "rows of another order start a new one",
+			csv:  "id,number,delivery_state\n1,10001,Open\n2,10002,\n1,10001,Shipped\n",
+			want: []domain.Order{
+				{ID: "1", Number: "10001", Deliveries: []domain.Delivery{{State: domain.DeliveryStateOpen}}},
+				{ID: "2", Number: "10002"},
+				{ID: "1", Number: "10001", Deliveries: []domain.Delivery{{State: domain.DeliveryStateShipped}}},
+			},
+		},
+		{
+			name: "header only",
+			csv:  "id,number\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fetchOrders(t, writeFile(t, "orders.csv", tt.csv), sources.FilterRequest{})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCSV_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want string
+	}{
+		{"empty file", "", "read header"},
+		{"missing id column", "number,state\n10001,Open\n", "missing [id] column"},
+		{"malformed order time", "id,created_at\n1,2020-10-01\n", "line 2 : column [created_at]"},
+		{"malformed delivery time", "id,delivery_state,delivery_updated_at\n1,Open,\n1,Open,yesterday\n",
+			"line 3 : column [delivery_updated_at]"},
+		{"malformed transaction time", "id,transaction_state,transaction_updated_at\n1,Paid,10/01/2020\n",
+			"line 2 : column [transaction_updated_at]"},
+		{"bare quote", "id,number\n1,10\"001\n", "read line 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fetch(t, writeFile(t, "orders.csv", tt.csv), sources.FilterRequest{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error [%v], want one containing [%s]", err, tt.want)
+			}
+		})
+	}
+}
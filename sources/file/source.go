@@ -0,0 +1,141 @@
+// Package file reads orders exported into a file as sources.Source.
+//
+// Supported formats by file extension:
+//   - .json: an array of domain.Order
+//   - .ndjson, .jsonl: one domain.Order per line, i.e. a snapshot written by the scan command
+//   - .csv: one row per order or per order item, see csv.go
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	"github.com/nikolayk812/shopware-orders-scanner/snapshot"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	"github.com/nikolayk812/shopware-orders-scanner/tracing"
+	"go.opentelemetry.io/otel/label"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pageSize matches the page size of Shopware searches
+const pageSize = 500
+
+type Source struct {
+	path string
+}
+
+func NewSource(path string) Source {
+	return Source{path: path}
+}
+
+// Fetch reads the file on every call, orders not matching the request are skipped.
+func (s Source) Fetch(ctx context.Context, req sources.FilterRequest, out chan<- sources.Page) (err error) {
+	ctx, span := tracing.Start(ctx, "file.Fetch", label.String("path", s.path))
+	defer func() { tracing.End(ctx, span, err) }()
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("os.Open [%s] : %w", s.path, err)
+	}
+	defer f.Close()
+
+	var page sources.Page
+	emit := func(order domain.Order) error {
+		page.Rows++
+		if matches(order, req) {
+			page.Orders = append(page.Orders, order)
+		}
+		if page.Rows < pageSize {
+			return nil
+		}
+		err := sources.Send(ctx, out, page)
+		page = sources.Page{}
+		return err
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(s.path)); ext {
+	case ".json":
+		var orders []domain.Order
+		if err := json.NewDecoder(f).Decode(&orders); err != nil {
+			return fmt.Errorf("json.Decode [%s] : %w", s.path, err)
+		}
+		for _, order := range orders {
+			if err := emit(order); err != nil {
+				return err
+			}
+		}
+	case ".ndjson", ".jsonl":
+		if err := snapshot.Read(f, emit); err != nil {
+			return fmt.Errorf("snapshot.Read [%s] : %w", s.path, err)
+		}
+	case ".csv":
+		if err := readCSV(f, emit); err != nil {
+			return fmt.Errorf("readCSV [%s] : %w", s.path, err)
+		}
+	default:
+		return fmt.Errorf("unsupported file extension [%s]", ext)
+	}
+
+	if page.Rows > 0 {
+		return sources.Send(ctx, out, page)
+	}
+	return nil
+}
+
+func matches(order domain.Order, req sources.FilterRequest) bool {
+	if req.IsLookup() {
+		return contains(req.OrderIDs, order.ID) || contains(req.OrderNumbers, order.Number)
+	}
+
+	if req.IsSweep() {
+		for _, state := range req.States {
+			if order.State == state {
+				return true
+			}
+		}
+		return false
+	}
+
+	if req.From.IsZero() && req.To.IsZero() {
+		return true
+	}
+
+	if req.IncludeCreated && inRange(order.CreatedAt, req) {
+		return true
+	}
+	if req.IncludeUpdated && inRange(order.UpdatedAt, req) {
+		return true
+	}
+	if req.IncludeDeliveryUpdated {
+		for _, d := range order.Deliveries {
+			if inRange(d.UpdatedAt, req) {
+				return true
+			}
+		}
+	}
+	if req.IncludeTransactionUpdated {
+		for _, tx := range order.Transactions {
+			if inRange(tx.UpdatedAt, req) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func inRange(t time.Time, req sources.FilterRequest) bool {
+	return !t.IsZero() && !t.Before(req.From) && !t.After(req.To)
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package file_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	"github.com/nikolayk812/shopware-orders-scanner/sources/file"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var day = time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+
+// writeFile writes content into a temporary file with the given name, removed when the test finishes.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "file-source")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile: %v", err)
+	}
+	return path
+}
+
+// fetch returns the pages sent by the source and its error.
+func fetch(t *testing.T, path string, req sources.FilterRequest) ([]sources.Page, error) {
+	t.Helper()
+
+	out := make(chan sources.Page, 100)
+	err := file.NewSource(path).Fetch(context.Background(), req, out)
+	close(out)
+
+	var pages []sources.Page
+	for page := range out {
+		pages = append(pages, page)
+	}
+	return pages, err
+}
+
+func fetchOrders(t *testing.T, path string, req sources.FilterRequest) []domain.Order {
+	t.Helper()
+
+	pages, err := fetch(t, path, req)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	var orders []domain.Order
+	for _, page := range pages {
+		orders = append(orders, page.Orders...)
+	}
+	return orders
+}
+
+func numbers(orders []domain.Order) []string {
+	result := []string{}
+	for _, o := range orders {
+		result = append(result, o.Number)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// fixtures are orders created on different days, 10003 has a delivery and 10004 a transaction updated on day 3.
+const fixtures = `[
+  {"ID": "1", "Number": "10001", "State": "Open", "CreatedAt": "2020-10-01T00:00:00Z", "UpdatedAt": "2020-10-01T00:00:00Z"},
+  {"ID": "2", "Number": "10002", "State": "In progress", "CreatedAt": "2020-10-02T00:00:00Z", "UpdatedAt": "2020-10-03T00:00:00Z"},
+  {"ID": "3", "Number": "10003", "State": "Done", "CreatedAt": "2020-09-01T00:00:00Z", "UpdatedAt": "2020-09-01T00:00:00Z",
+   "Deliveries": [{"ID": "d3", "State": "Shipped", "UpdatedAt": "2020-10-03T12:00:00Z"}]},
+  {"ID": "4", "Number": "10004", "State": "Done", "CreatedAt": "2020-09-01T00:00:00Z", "UpdatedAt": "2020-09-01T00:00:00Z",
+   "Transactions": [{"ID": "t4", "State": "Paid", "UpdatedAt": "2020-10-03T12:00:00Z"}]}
+]`
+
+func TestSource_Matches(t *testing.T) {
+	path := writeFile(t, "orders.json", fixtures)
+	window := func(from, to time.Time, req sources.FilterRequest) sources.FilterRequest {
+		req.From, req.To = from, to
+		return req
+	}
+
+	tests := []struct {
+		name string
+		req  sources.FilterRequest
+		want []string
+	}{
+		{"no window selects all", sources.FilterRequest{}, []string{"10001", "10002", "10003", "10004"}},
+		{"created, bounds are inclusive", window(day, day.AddDate(0, 0, 1), sources.FilterRequest{IncludeCreated: true}),
+			[]string{"10001", "10002"}},
+		{"updated", window(day.AddDate(0, 0, 2), day.AddDate(0, 0, 3), sources.FilterRequest{IncludeUpdated: true}),
+			[]string{"10002"}},
+		{"deliveries updated", window(day.AddDate(0, 0, 2), day.AddDate(0, 0, 3), sources.FilterRequest{IncludeDeliveryUpdated: true}),
+			[]string{"10003"}},
+		{"transactions updated", window(day.AddDate(0, 0, 2), day.AddDate(0, 0, 3), sources.FilterRequest{IncludeTransactionUpdated: true}),
+			[]string{"10004"}},
+		{"window without searches", window(day, day.AddDate(0, 0, 3), sources.FilterRequest{}), []string{}},
+		{"sweep", sources.FilterRequest{States: []domain.OrderState{domain.OrderStateOpen, domain.OrderStateInProgress}},
+			[]string{"10001", "10002"}},
+		{"lookup by ids and numbers ignores window and states", window(day.AddDate(1, 0, 0), day.AddDate(1, 0, 1), sources.FilterRequest{
+			IncludeCreated: true, OrderIDs: []string{"3"}, OrderNumbers: []string{"10004", "99999"},
+			States: []domain.OrderState{domain.OrderStateOpen}}),
+			[]string{"10003", "10004"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := numbers(fetchOrders(t, path, tt.req)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSource_Formats(t *testing.T) {
+	want := domain.Order{
+		ID:         "1",
+		Number:     "10001",
+		State:      domain.OrderStateDone,
+		CreatedAt:  day,
+		Deliveries: []domain.Delivery{{ID: "d1", State: domain.DeliveryStateShipped, TrackingCodes: []string{"code"}}},
+	}
+	ndjson := `{"ID": "1", "Number": "10001", "State": "Done", "CreatedAt": "2020-10-01T00:00:00Z",` +
+		` "Deliveries": [{"ID": "d1", "State": "Shipped", "TrackingCodes": ["code"]}]}` + "\n"
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"json", "orders.json", "[" + strings.TrimSpace(ndjson) + "]"},
+		{"ndjson", "orders.ndjson", ndjson},
+		{"jsonl", "orders.jsonl", ndjson},
+		{"csv", "orders.csv", "id,number,state,created_at,delivery_id,delivery_state,tracking_codes\n1,10001,Done,2020-10-01 00:00:00,d1,Shipped,code\n"},
+		{"extension in upper case", "ORDERS.JSON", "[" + strings.TrimSpace(ndjson) + "]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fetchOrders(t, writeFile(t, tt.file, tt.content), sources.FilterRequest{})
+			if !reflect.DeepEqual(got, []domain.Order{want}) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestSource_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"malformed json", "orders.json", `[{"ID": "1"`, "json.Decode"},
+		{"json of a single order", "orders.json", `{"ID": "1"}`, "json.Decode"},
+		{"malformed ndjson line", "orders.ndjson", "{\"ID\": \"1\"}\n{\"ID\": \n", "snapshot.Read"},
+		{"unsupported extension", "orders.xml", "<orders/>", "unsupported file extension [.xml]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fetch(t, writeFile(t, tt.file, tt.content), sources.FilterRequest{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error [%v], want one containing [%s]", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := fetch(t, filepath.Join(os.TempDir(), "missing-orders.json"), sources.FilterRequest{})
+		if err == nil || !strings.Contains(err.Error(), "os.Open") {
+			t.Errorf("got error [%v], want os.Open one", err)
+		}
+	})
+}
+
+// TestSource_Pages counts all rows read, including the orders not matching the request.
+func TestSource_Pages(t *testing.T) {
+	const count = 501
+
+	var lines []string
+	for i := 1; i <= count; i++ {
+		lines = append(lines, fmt.Sprintf(`{"ID": "%d", "Number": "%d", "State": "Open"}`, i, 10000+i))
+	}
+	path := writeFile(t, "orders.ndjson", strings.Join(lines, "\n"))
+
+	pages, err := fetch(t, path, sources.FilterRequest{OrderNumbers: []string{"10001", "10501"}})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	var rows, orders []int
+	for _, page := range pages {
+		rows = append(rows, page.Rows)
+		orders = append(orders, len(page.Orders))
+	}
+	if !reflect.DeepEqual(rows, []int{500, 1}) || !reflect.DeepEqual(orders, []int{1, 1}) {
+		t.Errorf("got pages of %v rows with %v orders, want [500 1] rows with [1 1] orders", rows, orders)
+	}
+}
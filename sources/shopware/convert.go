@@ -0,0 +1,132 @@
+package shopware
+
+import (
+	"fmt"
+	sw "github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	"time"
+)
+
+// fieldIncludes maps domain fields onto Shopware fields and associations.
+var fieldIncludes = map[domain.Field]sw.Includes{
+	domain.FieldState: {
+		sw.EntityOrder:             {"stateMachineState"},
+		sw.EntityStateMachineState: {"name"},
+	},
+	domain.FieldDeliveryState: {
+		sw.EntityOrder:             {"deliveries"},
+		sw.EntityOrderDelivery:     {"id", "stateMachineState"},
+		sw.EntityStateMachineState: {"name"},
+	},
+	domain.FieldDeliveryTrackingCodes: {
+		sw.EntityOrder:         {"deliveries"},
+		sw.EntityOrderDelivery: {"id", "trackingCodes"},
+	},
+	domain.FieldDeliveryUpdatedAt: {
+		sw.EntityOrder:         {"deliveries"},
+		sw.EntityOrderDelivery: {"id", "updatedAt"},
+	},
+	domain.FieldTransactionState: {
+		sw.EntityOrder:             {"transactions"},
+		sw.EntityOrderTransaction:  {"id", "stateMachineState"},
+		sw.EntityStateMachineState: {"name"},
+	},
+	domain.FieldTransactionCreatedAt: {
+		sw.EntityOrder:            {"transactions"},
+		sw.EntityOrderTransaction: {"id", "createdAt"},
+	},
+	domain.FieldDocumentFileType: {
+		sw.EntityOrder:    {"documents"},
+		sw.EntityDocument: {"fileType"},
+	},
+	domain.FieldDocumentFileName: {
+		sw.EntityOrder:    {"documents"},
+		sw.EntityDocument: {"config"},
+	},
+	domain.FieldLineItems: {
+		sw.EntityOrder:         {"lineItems"},
+		sw.EntityOrderLineItem: {"productId", "payload"},
+	},
+}
+
+// Includes returns Shopware includes of the fields, the order fields always available in domain.Order included.
+func Includes(fields []domain.Field) sw.Includes {
+	includes := []sw.Includes{{
+		sw.EntityOrder: {"id", "orderNumber", "salesChannelId", "createdAt", "updatedAt"},
+	}}
+	for _, f := range fields {
+		includes = append(includes, fieldIncludes[f])
+	}
+	return sw.MergeIncludes(includes...)
+}
+
+// ToDomain converts a Shopware order, i.e. one built by shopwaretest.OrderBuilder, into a source-neutral one.
+func ToDomain(o sw.Order) (domain.Order, error) {
+	createdAt, err := parseTime(o.CreatedAt)
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("order [%s] createdAt : %w", o.ID, err)
+	}
+	updatedAt, err := parseTime(o.UpdatedAt)
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("order [%s] updatedAt : %w", o.ID, err)
+	}
+
+	order := domain.Order{
+		ID:             o.ID,
+		Number:         o.Number,
+		SalesChannelID: o.SalesChannelID,
+		State:          domain.OrderState(o.StateMachineState.Name),
+		CreatedAt:      createdAt,
+		UpdatedAt:      updatedAt,
+	}
+
+	for _, d := range o.Deliveries {
+		dUpdatedAt, err := parseTime(d.UpdatedAt)
+		if err != nil {
+			return domain.Order{}, fmt.Errorf("order [%s] delivery [%s] updatedAt : %w", o.ID, d.ID, err)
+		}
+		order.Deliveries = append(order.Deliveries, domain.Delivery{
+			ID:            d.ID,
+			State:         domain.DeliveryState(d.StateMachineState.Name),
+			TrackingCodes: d.TrackingCodes,
+			UpdatedAt:     dUpdatedAt,
+		})
+	}
+
+	for _, tx := range o.Transactions {
+		txUpdatedAt, err := parseTime(tx.UpdatedAt)
+		if err != nil {
+			return domain.Order{}, fmt.Errorf("order [%s] transaction [%s] updatedAt : %w", o.ID, tx.ID, err)
+		}
+		order.Transactions = append(order.Transactions, domain.Transaction{
+			ID:        tx.ID,
+			State:     domain.TransactionState(tx.StateMachineState.Name),
+			CreatedAt: tx.CreatedAt,
+			UpdatedAt: txUpdatedAt,
+		})
+	}
+
+	for _, doc := range o.Documents {
+		order.Documents = append(order.Documents, domain.Document{
+			FileType: doc.FileType,
+			FileName: doc.Config.Custom.FileName,
+		})
+	}
+
+	for _, item := range o.LineItems {
+		order.LineItems = append(order.LineItems, domain.LineItem{
+			ProductID:     item.ProductID,
+			ProductNumber: item.Payload.ProductNumber,
+		})
+	}
+
+	return order, nil
+}
+
+// parseTime accepts empty values of fields not included in the response.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
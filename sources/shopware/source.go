@@ -0,0 +1,283 @@
+// Package shopware adapts the Shopware API client to sources.Source.
+package shopware
+
+import (
+	"context"
+	"fmt"
+	sw "github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	"github.com/nikolayk812/shopware-orders-scanner/tracing"
+	"go.opentelemetry.io/otel/label"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"time"
+)
+
+type Source struct {
+	orderCli sw.OrderService
+	limiter  chan struct{}
+}
+
+// NewSource creates a source which sends at most parallelism concurrent requests to Shopware.
+func NewSource(orderCli sw.OrderService, parallelism int) Source {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	return Source{
+		orderCli: orderCli,
+		limiter:  make(chan struct{}, parallelism),
+	}
+}
+
+// page is a single keyset page of a search: the number of rows the underlying search returned,
+// the id of its last row and either the orders or the ids of the orders it refers to.
+type page struct {
+	orders   []sw.Order
+	orderIDs []string
+	rows     int
+	lastID   string
+}
+
+type searchFunc func(ctx context.Context, field string, from, to time.Time, afterID string) (page, error)
+
+type search struct {
+	enabled bool
+	name    string
+	search  searchFunc
+	field   string
+}
+
+// Fetch runs all enabled searches concurrently, the first failing search cancels the others.
+func (s Source) Fetch(ctx context.Context, req sources.FilterRequest, out chan<- sources.Page) error {
+	if req.IsLookup() {
+		return s.fetchLookup(ctx, req, out)
+	}
+	if req.IsSweep() {
+		return s.fetchSweep(ctx, req.States, out)
+	}
+
+	searches := []search{
+		{req.IncludeUpdated, "SearchByTimeRange(updatedAt)", s.searchOrders, "updatedAt"},
+		{req.IncludeCreated, "SearchByTimeRange(createdAt)", s.searchOrders, "createdAt"},
+		{req.IncludeDeliveryUpdated, "searchOrdersByDeliveries", s.searchOrdersByDeliveries, "updatedAt"},
+		{req.IncludeTransactionUpdated, "searchOrdersByTransactions", s.searchOrdersByTransactions, "updatedAt"},
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, srch := range searches {
+		if !srch.enabled {
+			continue
+		}
+		srch := srch
+		g.Go(func() error {
+			if err := s.fetchSearch(ctx, g, srch, req.From, req.To, out); err != nil {
+				return fmt.Errorf("%s : %w", srch.name, err)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// fetchSearch fetches the pages of a search one after another, as every page depends on the cursor of the previous one.
+// Orders referred to by ids are looked up concurrently within the group.
+func (s Source) fetchSearch(ctx context.Context, g *errgroup.Group, srch search,
+	from, to time.Time, out chan<- sources.Page) (err error) {
+
+	ctx, span := tracing.Start(ctx, srch.name)
+	defer func() { tracing.End(ctx, span, err) }()
+
+	pages, rows, afterID := 0, 0, ""
+	for {
+		if err := s.acquire(ctx); err != nil {
+			return err
+		}
+		pageCtx, pageSpan := tracing.Start(ctx, "page", label.String("afterId", afterID))
+		pg, err := srch.search(pageCtx, srch.field, from, to, afterID)
+		pageSpan.SetAttributes(label.Int("rows", pg.rows))
+		tracing.End(pageCtx, pageSpan, err)
+		s.release()
+		if err != nil {
+			return fmt.Errorf("failed to get orders after [%s] : %w", afterID, err)
+		}
+		pages++
+		rows += pg.rows
+		afterID = pg.lastID
+		zap.L().Debug("got page", zap.String("source", srch.name), zap.Int("page", pages), zap.Int("rows", pg.rows))
+
+		if len(pg.orderIDs) > 0 {
+			// acquiring before spawning the lookup holds off fetching further pages while all slots are busy
+			if err := s.acquire(ctx); err != nil {
+				return err
+			}
+			orderIDs, pgRows := pg.orderIDs, pg.rows
+			g.Go(func() (err error) {
+				ctx, span := tracing.Start(ctx, "SearchByIDs", label.Int("orderIds", len(orderIDs)))
+				defer func() { tracing.End(ctx, span, err) }()
+
+				orders, err := s.orderCli.SearchByIDs(ctx, orderIDs)
+				s.release()
+				if err != nil {
+					return fmt.Errorf("SearchByIDs : %w", err)
+				}
+				return send(ctx, out, orders, pgRows)
+			})
+		} else if err := send(ctx, out, pg.orders, pg.rows); err != nil {
+			return err
+		}
+
+		if pg.rows < sw.MaxSearchLimit {
+			break
+		}
+	}
+
+	zap.L().Info("got rows", zap.String("source", srch.name), zap.Int("pages", pages), zap.Int("rows", rows))
+	span.SetAttributes(label.Int("pages", pages), label.Int("rows", rows))
+	return nil
+}
+
+// fetchSweep pages through all orders in the given states, keyset pagination keeps it cheap however many there are.
+func (s Source) fetchSweep(ctx context.Context, states []domain.OrderState, out chan<- sources.Page) error {
+	var swStates []sw.OrderState
+	for _, state := range states {
+		swStates = append(swStates, sw.OrderState(state))
+	}
+
+	srch := search{
+		enabled: true,
+		name:    "SearchByStates",
+		search: func(ctx context.Context, _ string, _, _ time.Time, afterID string) (page, error) {
+			orders, err := s.orderCli.SearchByStates(ctx, swStates, afterID)
+			if err != nil {
+				return page{}, err
+			}
+
+			p := page{orders: orders, rows: len(orders)}
+			if len(orders) > 0 {
+				p.lastID = orders[len(orders)-1].ID
+			}
+			return p, nil
+		},
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		if err := s.fetchSearch(ctx, g, srch, time.Time{}, time.Time{}, out); err != nil {
+			return fmt.Errorf("%s : %w", srch.name, err)
+		}
+		return nil
+	})
+	return g.Wait()
+}
+
+// fetchLookup gets orders by ids and numbers in chunks of at most sw.MaxSearchLimit.
+func (s Source) fetchLookup(ctx context.Context, req sources.FilterRequest, out chan<- sources.Page) error {
+	lookups := []struct {
+		name   string
+		values []string
+		search func(context.Context, []string) ([]sw.Order, error)
+	}{
+		{"SearchByIDs", req.OrderIDs, s.orderCli.SearchByIDs},
+		{"SearchByNumbers", req.OrderNumbers, s.orderCli.SearchByNumbers},
+	}
+
+	for _, l := range lookups {
+		for start := 0; start < len(l.values); start += sw.MaxSearchLimit {
+			end := start + sw.MaxSearchLimit
+			if end > len(l.values) {
+				end = len(l.values)
+			}
+
+			if err := s.acquire(ctx); err != nil {
+				return err
+			}
+			searchCtx, span := tracing.Start(ctx, l.name, label.Int("values", end-start))
+			orders, err := l.search(searchCtx, l.values[start:end])
+			tracing.End(searchCtx, span, err)
+			s.release()
+			if err != nil {
+				return fmt.Errorf("%s : %w", l.name, err)
+			}
+
+			if err := send(ctx, out, orders, len(orders)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s Source) searchOrders(ctx context.Context, field string, from, to time.Time, afterID string) (page, error) {
+	orders, err := s.orderCli.SearchByTimeRange(ctx, field, from, to, afterID)
+	if err != nil {
+		return page{}, err
+	}
+
+	p := page{orders: orders, rows: len(orders)}
+	if len(orders) > 0 {
+		p.lastID = orders[len(orders)-1].ID
+	}
+	return p, nil
+}
+
+func (s Source) searchOrdersByDeliveries(ctx context.Context, _ string, from, to time.Time, afterID string) (page, error) {
+	deliveries, err := s.orderCli.SearchDeliveriesByTimeRange(ctx, "updatedAt", from, to, afterID)
+	if err != nil {
+		return page{}, fmt.Errorf("failed to get deliveries by updatedAt : %w", err)
+	}
+
+	if len(deliveries) == 0 {
+		return page{}, nil
+	}
+
+	var orderIDs []string
+	for _, d := range deliveries {
+		orderIDs = append(orderIDs, d.OrderID)
+	}
+	return page{orderIDs: orderIDs, rows: len(deliveries), lastID: deliveries[len(deliveries)-1].ID}, nil
+}
+
+func (s Source) searchOrdersByTransactions(ctx context.Context, _ string, from, to time.Time, afterID string) (page, error) {
+	txs, err := s.orderCli.SearchTransactionsByTimeRange(ctx, "updatedAt", from, to, afterID)
+	if err != nil {
+		return page{}, fmt.Errorf("failed to get transactions by updatedAt : %w", err)
+	}
+
+	if len(txs) == 0 {
+		return page{}, nil
+	}
+
+	var orderIDs []string
+	for _, d := range txs {
+		orderIDs = append(orderIDs, d.OrderID)
+	}
+	return page{orderIDs: orderIDs, rows: len(txs), lastID: txs[len(txs)-1].ID}, nil
+}
+
+// acquire blocks until one of the parallelism slots for Shopware requests is free.
+func (s Source) acquire(ctx context.Context) error {
+	select {
+	case s.limiter <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s Source) release() {
+	<-s.limiter
+}
+
+func send(ctx context.Context, out chan<- sources.Page, orders []sw.Order, rows int) error {
+	page := sources.Page{Rows: rows}
+	for _, o := range orders {
+		order, err := ToDomain(o)
+		if err != nil {
+			return fmt.Errorf("ToDomain : %w", err)
+		}
+		page.Orders = append(page.Orders, order)
+	}
+	return sources.Send(ctx, out, page)
+}
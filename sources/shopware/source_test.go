@@ -0,0 +1,182 @@
+package shopware_test
+
+import (
+	"context"
+	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware"
+	"github.com/nikolayk812/shopware-orders-scanner/clients/shopware/shopwaretest"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	swsource "github.com/nikolayk812/shopware-orders-scanner/sources/shopware"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+var day = time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+
+// fetch returns ids of the fetched orders, sorted and without duplicates, and the rows read.
+func fetch(t *testing.T, source swsource.Source, req sources.FilterRequest) ([]string, int) {
+	t.Helper()
+
+	out := make(chan sources.Page, 100)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- source.Fetch(context.Background(), req, out)
+		close(out)
+	}()
+
+	seen, rows := map[string]bool{}, 0
+	for page := range out {
+		rows += page.Rows
+		for _, o := range page.Orders {
+			seen[o.ID] = true
+		}
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	ids := []string{}
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, rows
+}
+
+func TestSource_Fetch(t *testing.T) {
+	before, within := day.Add(-time.Hour), day.Add(time.Hour)
+	service := shopwaretest.NewOrderService(
+		shopwaretest.NewOrder(shopwaretest.ID(1)).Number("10001").CreatedAt(within).UpdatedAt(within).Build(),
+		shopwaretest.NewOrder(shopwaretest.ID(2)).Number("10002").CreatedAt(before).UpdatedAt(within).Build(),
+		shopwaretest.NewOrder(shopwaretest.ID(3)).Number("10003").CreatedAt(before).UpdatedAt(before).
+			Delivery(shopwaretest.NewDelivery(shopware.OrderDeliveryStateShipped).UpdatedAt(within)).
+			Delivery(shopwaretest.NewDelivery(shopware.OrderDeliveryStateShipped).UpdatedAt(within)).Build(),
+		shopwaretest.NewOrder(shopwaretest.ID(4)).Number("10004").State(shopware.OrderStateDone).CreatedAt(before).UpdatedAt(before).
+			Transaction(shopwaretest.NewTransaction(shopware.OrderTransactionStatePaid).UpdatedAt(within)).Build(),
+	)
+	source := swsource.NewSource(service, 2)
+	window := func(req sources.FilterRequest) sources.FilterRequest {
+		req.From, req.To = day, day.AddDate(0, 0, 1)
+		return req
+	}
+
+	tests := []struct {
+		name     string
+		req      sources.FilterRequest
+		wantIDs  []string
+		wantRows int
+	}{
+		{"created", window(sources.FilterRequest{IncludeCreated: true}), []string{shopwaretest.ID(1)}, 1},
+		{"updated", window(sources.FilterRequest{IncludeUpdated: true}), []string{shopwaretest.ID(1), shopwaretest.ID(2)}, 2},
+		{"deliveries updated, rows are deliveries", window(sources.FilterRequest{IncludeDeliveryUpdated: true}), []string{shopwaretest.ID(3)}, 2},
+		{"transactions updated", window(sources.FilterRequest{IncludeTransactionUpdated: true}), []string{shopwaretest.ID(4)}, 1},
+		{"all searches", window(sources.FilterRequest{IncludeCreated: true, IncludeUpdated: true, IncludeDeliveryUpdated: true, IncludeTransactionUpdated: true}),
+			[]string{shopwaretest.ID(1), shopwaretest.ID(2), shopwaretest.ID(3), shopwaretest.ID(4)}, 6},
+		{"sweep", sources.FilterRequest{States: []domain.OrderState{domain.OrderStateDone}}, []string{shopwaretest.ID(4)}, 1},
+		{"lookup by ids and numbers", sources.FilterRequest{OrderIDs: []string{shopwaretest.ID(1)}, OrderNumbers: []string{"10003", "99999"}},
+			[]string{shopwaretest.ID(1), shopwaretest.ID(3)}, 2},
+		{"lookup ignores the window", window(sources.FilterRequest{IncludeCreated: true, OrderNumbers: []string{"10004"}}), []string{shopwaretest.ID(4)}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, rows := fetch(t, source, tt.req)
+			if !reflect.DeepEqual(ids, tt.wantIDs) || rows != tt.wantRows {
+				t.Errorf("got %v of %d rows, want %v of %d rows", ids, rows, tt.wantIDs, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestSource_FetchPages(t *testing.T) {
+	const count = shopware.MaxSearchLimit + 1
+
+	var orders []shopware.Order
+	for i := 1; i <= count; i++ {
+		orders = append(orders, shopwaretest.NewOrder(shopwaretest.ID(i)).UpdatedAt(day).Build())
+	}
+	source := swsource.NewSource(shopwaretest.NewOrderService(orders...), 1)
+
+	ids, rows := fetch(t, source, sources.FilterRequest{From: day, To: day, IncludeUpdated: true})
+	if len(ids) != count || rows != count {
+		t.Errorf("got %d orders of %d rows, want %d of both", len(ids), rows, count)
+	}
+}
+
+func TestToDomain(t *testing.T) {
+	created := time.Date(2020, 10, 1, 8, 15, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		order   shopware.Order
+		want    domain.Order
+		wantErr bool
+	}{
+		{
+			name: "all fields",
+			order: shopwaretest.NewOrder(shopwaretest.ID(1)).Number("10001").SalesChannel("channel").
+				State(shopware.OrderStateDone).CreatedAt(created).UpdatedAt(created.Add(time.Hour)).
+				Delivery(shopwaretest.NewDelivery(shopware.OrderDeliveryStateShipped).ID("d1").TrackingCodes("code").UpdatedAt(created)).
+				Transaction(shopwaretest.NewTransaction(shopware.OrderTransactionStatePaid).ID("t1").CreatedAt(created).UpdatedAt(created)).
+				Document("pdf", "delivery_note.pdf").
+				LineItem("p1", "SW10001").
+				Build(),
+			want: domain.Order{
+				ID:             shopwaretest.ID(1),
+				Number:         "10001",
+				SalesChannelID: "channel",
+				State:          domain.OrderStateDone,
+				CreatedAt:      created,
+				UpdatedAt:      created.Add(time.Hour),
+				Deliveries:     []domain.Delivery{{ID: "d1", State: domain.DeliveryStateShipped, TrackingCodes: []string{"code"}, UpdatedAt: created}},
+				Transactions:   []domain.Transaction{{ID: "t1", State: domain.TransactionStatePaid, CreatedAt: created, UpdatedAt: created}},
+				Documents:      []domain.Document{{FileType: "pdf", FileName: "delivery_note.pdf"}},
+				LineItems:      []domain.LineItem{{ProductID: "p1", ProductNumber: "SW10001"}},
+			},
+		},
+		{
+			name:  "fields not included",
+			order: shopware.Order{ID: shopwaretest.ID(2)},
+			want:  domain.Order{ID: shopwaretest.ID(2)},
+		},
+		{
+			name:    "invalid time",
+			order:   shopware.Order{ID: shopwaretest.ID(3), CreatedAt: "yesterday"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := swsource.ToDomain(tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			// times parsed from the API carry a fixed zone of the same instant
+			if !got.CreatedAt.Equal(tt.want.CreatedAt) || !got.UpdatedAt.Equal(tt.want.UpdatedAt) {
+				t.Errorf("got created at %s and updated at %s, want %s and %s", got.CreatedAt, got.UpdatedAt, tt.want.CreatedAt, tt.want.UpdatedAt)
+			}
+			got.CreatedAt, got.UpdatedAt = tt.want.CreatedAt, tt.want.UpdatedAt
+			for i := range got.Deliveries {
+				got.Deliveries[i].UpdatedAt = normalize(got.Deliveries[i].UpdatedAt, tt.want.Deliveries[i].UpdatedAt)
+			}
+			for i := range got.Transactions {
+				got.Transactions[i].UpdatedAt = normalize(got.Transactions[i].UpdatedAt, tt.want.Transactions[i].UpdatedAt)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// normalize returns want if got is the same instant, so DeepEqual compares instants rather than zones.
+func normalize(got, want time.Time) time.Time {
+	if got.Equal(want) {
+		return want
+	}
+	return got
+}
@@ -0,0 +1,57 @@
+package sources
+
+import (
+	"context"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	"time"
+)
+
+// Source fetches orders from a shop system or an export.
+type Source interface {
+	// Fetch sends pages of orders matching the request to out until exhausted, it must not close out.
+	// The same order may be sent more than once.
+	Fetch(ctx context.Context, req FilterRequest, out chan<- Page) error
+}
+
+// FilterRequest selects orders created or updated within the time range,
+// the zero time range selects all orders of file based sources.
+// If any order ids or numbers are given, only those orders are selected regardless of the time range.
+// Otherwise if any states are given, all orders in those states are selected regardless of the time range.
+type FilterRequest struct {
+	From, To                  time.Time
+	IncludeCreated            bool
+	IncludeUpdated            bool
+	IncludeDeliveryUpdated    bool
+	IncludeTransactionUpdated bool
+
+	OrderIDs     []string
+	OrderNumbers []string
+
+	States []domain.OrderState
+}
+
+// IsLookup reports whether specific orders are requested.
+func (r FilterRequest) IsLookup() bool {
+	return len(r.OrderIDs) > 0 || len(r.OrderNumbers) > 0
+}
+
+// IsSweep reports whether all orders in the given states are requested.
+func (r FilterRequest) IsSweep() bool {
+	return !r.IsLookup() && len(r.States) > 0
+}
+
+// Page is a page of orders and the number of rows read to get them, i.e. deliveries referring to the orders.
+type Page struct {
+	Orders []domain.Order
+	Rows   int
+}
+
+// Send blocks until out accepts the page or ctx is done.
+func Send(ctx context.Context, out chan<- Page, page Page) error {
+	select {
+	case out <- page:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/config"
+	"github.com/nikolayk812/shopware-orders-scanner/domain"
+	"github.com/nikolayk812/shopware-orders-scanner/metrics"
+	"github.com/nikolayk812/shopware-orders-scanner/orders"
+	"github.com/nikolayk812/shopware-orders-scanner/sources"
+	"github.com/nikolayk812/shopware-orders-scanner/tracing"
+	"go.opentelemetry.io/otel/label"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"time"
+)
+
+// sweepStates are the non-final order states, shipped but not done orders are still in progress.
+var sweepStates = []domain.OrderState{domain.OrderStateOpen, domain.OrderStateInProgress}
+
+// runSweep checks all open and in progress orders regardless of when they were created or updated,
+// to catch orders stuck for longer than the update window of the scan command, e.g. run weekly.
+func runSweep(args []string) error {
+	flags := flag.NewFlagSet("sweep", flag.ContinueOnError)
+	snapshotPath := flags.String("snapshot", "", "write scanned orders to this NDJSON file for the recheck command")
+	pf := addProfileFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return configError(err)
+	}
+
+	var cfg mainConfig
+	if err := config.Parse("local.env", &cfg); err != nil {
+		return configError(fmt.Errorf("config.Parse: %w", err))
+	}
+	if err := multierr.Combine(validateExit(cfg.Scan), validateTemplates()); err != nil {
+		return err
+	}
+	shops, err := pf.load()
+	if err != nil {
+		return err
+	}
+	if len(shops.Profiles) > 1 && *snapshotPath != "" {
+		return configError(errors.New("-snapshot needs a single profile, select it by -profile"))
+	}
+
+	runs, err := runShops(context.Background(), cfg, shops, *snapshotPath, sweep)
+	return checkShops(cfg.Scan, runs, err)
+}
+
+// sweep checks all orders in sweepStates and reports suspicious ones.
+func sweep(ctx context.Context, cfg mainConfig, snapshotPath string) (_ orders.ScanResult, err error) {
+	now, shop := time.Now(), cfg.Profile.Shopware.BaseURL
+	ctx, span := tracing.Start(ctx, "sweep", label.String("shop", shop))
+	defer func() {
+		metrics.ObserveRun("sweep", shop, now, err)
+		tracing.End(ctx, span, err)
+	}()
+
+	service, closeService, err := buildService(cfg, snapshotPath)
+	if err != nil {
+		return orders.ScanResult{}, fmt.Errorf("buildService : %w", err)
+	}
+	defer closeService()
+	if service, err = watchFindings(cfg, service); err != nil {
+		return orders.ScanResult{}, err
+	}
+
+	result, err := service.ScanOrders(ctx, sources.FilterRequest{States: sweepStates})
+	if err != nil {
+		return orders.ScanResult{}, sourceError(fmt.Errorf("failed to sweep %v orders : %w", sweepStates, err))
+	}
+	zap.L().Info("detected suspicious orders", zap.String("run", "sweep"), zap.String("shop", shop), zap.Int("suspicious", len(result.Orders)),
+		zap.Int("scanned", result.Scanned), zap.Int("pages", result.Pages), zap.Int("rows", result.Rows))
+	metrics.ObserveResult("sweep", shop, result, buildEngine(cfg.Profile.Checks).Rules())
+
+	if err := report(ctx, cfg, now, result); err != nil {
+		// still part of a combined report of several shops
+		return result, err
+	}
+	return result, nil
+}
@@ -0,0 +1,86 @@
+// Package tracing exports OpenTelemetry spans of scans over OTLP, spans are no-ops unless Init has enabled it.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/config"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/label"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.uber.org/zap"
+	"math/rand"
+)
+
+const (
+	tracerName  = "github.com/nikolayk812/shopware-orders-scanner"
+	serviceName = "shopware-orders-scanner"
+)
+
+// checkSampleRatio is the share of orders whose evaluation is traced, set by Init
+var checkSampleRatio float64
+
+// Init installs the OTLP exporter if tracing is enabled, the returned func flushes and stops it.
+func Init(cfg config.Tracing) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlp.ExporterOption{otlp.WithAddress(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlp.WithInsecure())
+	}
+	exporter, err := otlp.NewExporter(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("otlp.NewExporter [%s] : %w", cfg.Endpoint, err)
+	}
+
+	batcher := sdktrace.NewBatchSpanProcessor(exporter)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{
+			DefaultSampler: sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio)),
+		}),
+		sdktrace.WithResource(resource.New(semconv.ServiceNameKey.String(serviceName))),
+		sdktrace.WithSpanProcessor(batcher),
+	)
+	global.SetTracerProvider(provider)
+	global.SetErrorHandler(errorHandler{})
+	checkSampleRatio = cfg.CheckSampleRatio
+
+	return func(ctx context.Context) error {
+		// exports the spans still queued
+		batcher.Shutdown()
+		return exporter.Shutdown(ctx)
+	}, nil
+}
+
+// Start starts a span of the scanner.
+func Start(ctx context.Context, name string, labels ...label.KeyValue) (context.Context, trace.Span) {
+	return global.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(labels...))
+}
+
+// End marks the span failed if err is not nil and ends it.
+func End(ctx context.Context, span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(ctx, err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// SampleCheck reports whether to trace evaluation of the next order, checking is too fine-grained to trace every order.
+func SampleCheck() bool {
+	return checkSampleRatio > 0 && rand.Float64() < checkSampleRatio
+}
+
+// errorHandler logs failed exports, which don't fail scans.
+type errorHandler struct{}
+
+func (errorHandler) Handle(err error) {
+	zap.L().Warn("failed to export traces", zap.Error(err))
+}
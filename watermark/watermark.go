@@ -0,0 +1,92 @@
+// Package watermark persists per shop the upper bound of the last successful scan,
+// so the next scan continues from there regardless of when it runs.
+package watermark
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/nikolayk812/shopware-orders-scanner/dryrun"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+type watermark struct {
+	Shop         string    `json:"shop"`
+	ScannedUntil time.Time `json:"scannedUntil"`
+}
+
+// Store keeps a JSON file per shop in a local directory.
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) Store {
+	return Store{dir: dir}
+}
+
+// Load returns the upper bound of the last successful scan of the shop, the zero time if the shop was never scanned.
+func (s Store) Load(shop string) (time.Time, error) {
+	data, err := ioutil.ReadFile(s.path(shop))
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ioutil.ReadFile : %w", err)
+	}
+
+	var w watermark
+	if err := json.Unmarshal(data, &w); err != nil {
+		return time.Time{}, fmt.Errorf("json.Unmarshal [%s] : %w", s.path(shop), err)
+	}
+	return w.ScannedUntil, nil
+}
+
+// Save advances the watermark of the shop, the file is replaced atomically so a crash never leaves it truncated.
+// A dry run keeps the watermark, so the next real scan covers its window.
+func (s Store) Save(shop string, scannedUntil time.Time) error {
+	if dryrun.Skip("watermark save", zap.String("shop", shop), zap.Time("scanned_until", scannedUntil)) {
+		return nil
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("os.MkdirAll [%s] : %w", s.dir, err)
+	}
+
+	data, err := json.MarshalIndent(watermark{Shop: shop, ScannedUntil: scannedUntil.UTC()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent : %w", err)
+	}
+
+	tmp, err := ioutil.TempFile(s.dir, ".watermark-*")
+	if err != nil {
+		return fmt.Errorf("ioutil.TempFile : %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("tmp.Write : %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("tmp.Close : %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(shop)); err != nil {
+		return fmt.Errorf("os.Rename : %w", err)
+	}
+	return nil
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// path names the file after the shop host, falling back to the whole shop string if it is not a URL.
+func (s Store) path(shop string) string {
+	name := shop
+	if u, err := url.Parse(shop); err == nil && u.Host != "" {
+		name = u.Host + u.Path
+	}
+	return filepath.Join(s.dir, unsafeChars.ReplaceAllString(name, "_")+".json")
+}